@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
@@ -15,45 +17,68 @@ var CCstr string
 type PS struct { // Petsitting chaincode
 }
 
-type TradeRec struct { // Trade record (KEY: PSID#CSID#TC)
-	PSID       string // Petsitter ID
-	PSNickname string // Petsitter Nickname
-	CSID       string // Consumer ID
-	TS         string // Transaction start time
-	TE         string // Transaction end time
-	TC         string // Transaction complete time
-	TA         string // Transaction amount
-	TH         string // Transaction history
+type TradeRec struct { // Trade record (KEY: PSID#CSID#거래 ID)
+	TradeID       string // Unique trade ID (TC for legacy trades)
+	PSID          string // Petsitter ID
+	PSNickname    string // Petsitter Nickname
+	CSID          string // Consumer ID
+	TS            string // Transaction start time
+	TE            string // Transaction end time
+	TC            string // Transaction complete time
+	TA            string // Transaction amount
+	TH            string // Transaction history
+	Status        string // Trade status (booked, accepted, cancelled, disputed, resolved)
+	Refund        string // Escrow amount returned to the consumer
+	Payout        string // Escrow amount released to the petsitter
+	HomeID        string // Booked home
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
 }
 
-type Petsitter struct { // User information (KEY: User email)
-	Nickname string
-	CostL    string
-	CostM    string
-	CostS    string
-	Start    string
-	End      string
-	Except   string
-	TotalNum string
-	NumL     string
-	NumM     string
-	NumS     string
-	Home     string
-	HomeInfo string
-	SaveTime string
+type Petsitter struct { // User information (KEY: User ID)
+	Nickname      string
+	CostL         string
+	CostM         string
+	CostS         string
+	Start         string
+	End           string
+	Except        string
+	TotalNum      string
+	NumL          string
+	NumM          string
+	NumS          string
+	Home          string
+	HomeInfo      string
+	SaveTime      string
+	Status        string // active, paused, suspended, deleted
+	StatusNote    string // Suspension reason
+	Rating        string // Average review score
+	ReviewNum     string // Number of reviews
+	ReviewSum     string // Sum of review scores
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
 }
 
-type HomeAsset struct { // Information about home (KEY: User email#home)
-	State    string
-	City     string
-	Street   string
-	Adt      string
-	Code     string
-	Type     string
-	Room     string
-	Elevator string
-	Parking  string
-	SaveTime string
+type HomeAsset struct { // Information about home (KEY: User ID#home or User ID#home#집 ID)
+	ID            string // Home ID (default for User ID#home)
+	Name          string
+	State         string
+	City          string
+	Street        string // Stored in User ID#addr#집 ID
+	Adt           string // Stored in User ID#addr#집 ID
+	Code          string // Stored in User ID#addr#집 ID
+	Type          string
+	Room          string
+	Elevator      string
+	Parking       string
+	Lat           string // Latitude (optional)
+	Lng           string // Longitude (optional)
+	SaveTime      string
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
 }
 
 func main() {
@@ -81,13 +106,26 @@ func (t *PS) Init(stub shim.ChaincodeStubInterface, function string, args []stri
 		fmt.Println()
 		return nil, errors.New("[INIT] Incorrect number of arguments. Expecting 0")
 	}
+	if err := checkUIDSecret(); err != nil {
+		return nil, errors.New("[INIT] " + err.Error())
+	}
 	CCstr = "/"
+	conf, _ := stub.GetState("_regions")
+	if conf == nil {
+		jsonAsBytes, _ := json.Marshal(defaultRegions)
+		stub.PutState("_regions", jsonAsBytes)
+	}
+	// 이전 버전 레코드는 관리자가 migrate_schema 로 나누어 변환한다
 	fmt.Println("=======================<< Start chaincode >>========================")
 
 	return nil, nil
 }
 
 func (t *PS) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	if err := checkUIDSecret(); err != nil {
+		return nil, errors.New("[INVOKE] " + err.Error())
+	}
+	args = resolveUserArgs(stub, function, args)
 	if function == "save_petsitter" {
 		return t.save_petsitter(stub, args)
 	} else if function == "modify_petsitter" {
@@ -114,6 +152,56 @@ func (t *PS) Invoke(stub shim.ChaincodeStubInterface, function string, args []st
 		return t.save_home(stub, args)
 	} else if function == "modify_home" {
 		return t.modify_home(stub, args)
+	} else if function == "set_cancel_policy" {
+		return t.set_cancel_policy(stub, args)
+	} else if function == "cancel_booking" {
+		return t.cancel_booking(stub, args)
+	} else if function == "submit_review" {
+		return t.submit_review(stub, args)
+	} else if function == "submit_consumer_review" {
+		return t.submit_consumer_review(stub, args)
+	} else if function == "reveal_reviews" {
+		return t.reveal_reviews(stub, args)
+	} else if function == "open_dispute" {
+		return t.open_dispute(stub, args)
+	} else if function == "add_dispute_evidence" {
+		return t.add_dispute_evidence(stub, args)
+	} else if function == "resolve_dispute" {
+		return t.resolve_dispute(stub, args)
+	} else if function == "set_petsitter_status" {
+		return t.set_petsitter_status(stub, args)
+	} else if function == "suspend_petsitter" {
+		return t.suspend_petsitter(stub, args)
+	} else if function == "reinstate_petsitter" {
+		return t.reinstate_petsitter(stub, args)
+	} else if function == "save_named_home" {
+		return t.save_named_home(stub, args)
+	} else if function == "modify_named_home" {
+		return t.modify_named_home(stub, args)
+	} else if function == "delete_named_home" {
+		return t.delete_named_home(stub, args)
+	} else if function == "set_home_location" {
+		return t.set_home_location(stub, args)
+	} else if function == "set_regions" {
+		return t.set_regions(stub, args)
+	} else if function == "accept_booking" {
+		return t.accept_booking(stub, args)
+	} else if function == "migrate_user" {
+		return t.migrate_user(stub, args)
+	} else if function == "erase_user" {
+		return t.erase_user(stub, args)
+	} else if function == "migrate_schema" {
+		return t.migrate_schema(stub, args)
+	} else if function == "reindex" {
+		return t.reindex(stub, args)
+	} else if function == "bulk_import" {
+		return t.bulk_import(stub, args)
+	} else if function == "register_petsitter" {
+		return t.register_petsitter(stub, args)
+	} else if function == "patch_home" {
+		return t.patch_home(stub, args)
+	} else if function == "patch_petsitter" {
+		return t.patch_petsitter(stub, args)
 	}
 
 	fmt.Println()
@@ -127,6 +215,10 @@ func (t *PS) Invoke(stub shim.ChaincodeStubInterface, function string, args []st
 }
 
 func (t *PS) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	if err := checkUIDSecret(); err != nil {
+		return nil, errors.New("[QUERY] " + err.Error())
+	}
+	args = resolveUserArgs(stub, function, args)
 	if function == "read_petsitter" {
 		return t.read_petsitter(stub, args)
 	} else if function == "read_house" {
@@ -137,6 +229,42 @@ func (t *PS) Query(stub shim.ChaincodeStubInterface, function string, args []str
 		return t.search_bytotal(stub, args)
 	} else if function == "search_byregion" {
 		return t.search_byregion(stub, args)
+	} else if function == "read_cancel_policy" {
+		return t.read_cancel_policy(stub, args)
+	} else if function == "list_reviews" {
+		return t.list_reviews(stub, args)
+	} else if function == "read_consumer_rating" {
+		return t.read_consumer_rating(stub, args)
+	} else if function == "read_dispute" {
+		return t.read_dispute(stub, args)
+	} else if function == "search_orphans" {
+		return t.search_orphans(stub, args)
+	} else if function == "read_named_home" {
+		return t.read_named_home(stub, args)
+	} else if function == "list_homes" {
+		return t.list_homes(stub, args)
+	} else if function == "search_nearby" {
+		return t.search_nearby(stub, args)
+	} else if function == "read_regions" {
+		return t.read_regions(stub, args)
+	} else if function == "read_home_address" {
+		return t.read_home_address(stub, args)
+	} else if function == "read_user_id" {
+		return t.read_user_id(stub, args)
+	} else if function == "read_erasure_receipt" {
+		return t.read_erasure_receipt(stub, args)
+	} else if function == "search_tran_by_consumer" {
+		return t.search_tran_by_consumer(stub, args)
+	} else if function == "petsitter_stats" {
+		return t.petsitter_stats(stub, args)
+	} else if function == "analytics_sitters" {
+		return t.analytics_sitters(stub, args)
+	} else if function == "analytics_prices" {
+		return t.analytics_prices(stub, args)
+	} else if function == "analytics_bookings" {
+		return t.analytics_bookings(stub, args)
+	} else if function == "export_records" {
+		return t.export_records(stub, args)
 	}
 	fmt.Println()
 	fmt.Println("=======================================================================")
@@ -158,7 +286,7 @@ func (t *PS) save_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]
 		fmt.Println()
 		return nil, errors.New("[Petsitter INSSERT] Incorrect number of arguments. Expecting 14")
 	}
-	conf, _ := stub.GetState(args[0])
+	conf, _ := stub.GetState(userID(stub, args[0]))
 	if conf != nil {
 		fmt.Println()
 		fmt.Println("=======================================================================")
@@ -168,83 +296,52 @@ func (t *PS) save_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]
 		fmt.Println()
 		return nil, errors.New("[Petsitter INSSERT] Already exist Petsitter")
 	}
+	id := registerUser(stub, args[0])
 	time := time.Now()
-	petsitter := Petsitter{args[1], args[2], args[3], args[4], args[5], args[6], args[7], args[8], args[9], args[10], args[11], args[12], args[13], time.String()}
-	jsonAsBytes, _ := json.Marshal(petsitter)
-	stub.PutState(args[0], jsonAsBytes)
+	petsitter := Petsitter{args[1], args[2], args[3], args[4], args[5], args[6], args[7], args[8], args[9], args[10], args[11], args[12], args[13], time.String(), "active", "", "0", "0", "0", docPetsitter, schemaVersion, ""}
+	key, err := piiKey(stub)
+	if err == nil {
+		err = encryptFields(stub, key, id, petsitterPII(&petsitter))
+	}
+	if err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Petsitter Insert >>>>")
+		fmt.Println("                           Field encryption error")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter INSSERT] " + err.Error())
+	}
+	jsonAsBytes, _ := marshalDoc(&petsitter)
+	stub.PutState(id, jsonAsBytes)
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                <<<< Petsitter Insert chaincode >>>>")
 	fmt.Println("======================================================================")
-	CCstr = CCstr + args[0] + "/"
+	CCstr = CCstr + id + "/"
 	stub.PutState("_CCstr", []byte(CCstr))
-	return nil, nil
+	return []byte(id), nil
 }
 
 func (t *PS) modify_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 14 {
+	if len(args) != 14 && len(args) != 15 {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Petsitter Change >>>>")
-		fmt.Println("               Incorrect number of arguments. Expecting 14")
+		fmt.Println("            Incorrect number of arguments. Expecting 14 or 15")
 		fmt.Println("=======================================================================")
 		fmt.Println()
-		return nil, errors.New("[Petsitter CHANGE] Incorrect number of arguments. Expecting 14")
+		return nil, errors.New("[Petsitter CHANGE] Incorrect number of arguments. Expecting 14 or 15")
 	}
-	confUser, _ := stub.GetState(args[0])
-	if confUser == nil {
+	p := argsPatch([]string{"Nickname", "CostL", "CostM", "CostS", "Start", "End", "Except", "TotalNum", "NumL", "NumM", "NumS", "Home", "HomeInfo"}, args[1:], true)
+	if _, err := patchPetsitter(stub, args[0], p, optArg(args, 14)); err != nil {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Petsitter Change >>>>")
-		fmt.Println("                               Not exist Petsitter")
+		fmt.Println("                               Patch failed")
 		fmt.Println("=======================================================================")
 		fmt.Println()
-		return nil, errors.New("[Petsitter CHANGE] Not exist Petsitter")
-	}
-	petsitter := Petsitter{}
-	json.Unmarshal(confUser, &petsitter)
-	if args[1] != "none" {
-		petsitter.Nickname = args[1]
-	}
-	if args[2] != "none" {
-		petsitter.CostL = args[2]
-	}
-	if args[3] != "none" {
-		petsitter.CostM = args[3]
-	}
-	if args[4] != "none" {
-		petsitter.CostS = args[4]
-	}
-	if args[5] != "none" {
-		petsitter.Start = args[5]
-	}
-	if args[6] != "none" {
-		petsitter.End = args[6]
-	}
-	if args[7] != "none" {
-		petsitter.Except = args[7]
-	}
-	if args[8] != "none" {
-		petsitter.TotalNum = args[8]
+		return nil, errors.New("[Petsitter CHANGE] " + err.Error())
 	}
-	if args[9] != "none" {
-		petsitter.NumL = args[9]
-	}
-	if args[10] != "none" {
-		petsitter.NumM = args[10]
-	}
-	if args[11] != "none" {
-		petsitter.NumS = args[11]
-	}
-	if args[12] != "none" {
-		petsitter.Home = args[12]
-	}
-	if args[13] != "none" {
-		petsitter.HomeInfo = args[13]
-	}
-	petsitter.SaveTime = time.Now().String()
-
-	jsonAsBytes, _ := json.Marshal(petsitter)
-	stub.PutState(args[0], jsonAsBytes)
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                <<<< Petsitter Change chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -262,6 +359,15 @@ func (t *PS) delete_petsitter(stub shim.ChaincodeStubInterface, args []string) (
 		fmt.Println()
 		return nil, errors.New("[Petsitter DELETE] Incorrect number of arguments. Expecting 1")
 	}
+	if !selfOrAdmin(stub, args[0]) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Petsitter Delete >>>>")
+		fmt.Println("                              Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter DELETE] Not allowed caller")
+	}
 	userID := args[0]
 	conf, _ := stub.GetState(userID)
 	if conf == nil {
@@ -273,7 +379,38 @@ func (t *PS) delete_petsitter(stub shim.ChaincodeStubInterface, args []string) (
 		fmt.Println()
 		return nil, errors.New("[Petsitter DELETE] Not exist Petsitter")
 	}
-	stub.DelState(args[0])
+	if petsitterDeleted(conf) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Petsitter Delete >>>>")
+		fmt.Println("                            Already deleted Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter DELETE] Already deleted Petsitter")
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Petsitter DELETE] " + err.Error())
+	}
+	if active := activeBookings(stub, userID+"#t", now); len(active) > 0 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Petsitter Delete >>>>")
+		fmt.Println("                           Exist active booking")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter DELETE] Exist active booking: " + strings.Join(active, ","))
+	}
+	petsitter := Petsitter{}
+	json.Unmarshal(conf, &petsitter)
+	petsitter.Status = "deleted"
+	petsitter.SaveTime = now.String()
+	jsonAsBytes, _ := marshalDoc(&petsitter)
+	stub.PutState(userID, jsonAsBytes)
+	stub.DelState(userID + "#policy")
+	for _, hid := range homeIDs(stub, userID) {
+		deleteHome(stub, userID, hid)
+	}
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                <<<< Petsitter Delete chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -303,17 +440,16 @@ func (t *PS) save_home_address(stub shim.ChaincodeStubInterface, args []string)
 		fmt.Println()
 		return nil, errors.New("[Home INSSERT] Incorrect number of arguments. Expecting 6")
 	}
-	conf, _ := stub.GetState(args[0] + "#home")
-	homeAsset := HomeAsset{}
-	json.Unmarshal(conf, &homeAsset)
-	homeAsset.State = args[1]
-	homeAsset.City = args[2]
-	homeAsset.Street = args[3]
-	homeAsset.Adt = args[4]
-	homeAsset.Code = args[5]
-	homeAsset.SaveTime = time.Now().String()
-	jsonAsBytes, _ := json.Marshal(homeAsset)
-	stub.PutState(args[0]+"#home", jsonAsBytes)
+	p := argsPatch([]string{"State", "City", "Street", "Adt", "Code"}, args[1:], false)
+	if _, err := patchHome(stub, args[0], defaultHome, p, ""); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] " + err.Error())
+	}
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Insert chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -331,14 +467,16 @@ func (t *PS) save_home_room(stub shim.ChaincodeStubInterface, args []string) ([]
 		fmt.Println()
 		return nil, errors.New("[Home INSSERT] Incorrect number of arguments. Expecting 3")
 	}
-	conf, _ := stub.GetState(args[0] + "#home")
-	homeAsset := HomeAsset{}
-	json.Unmarshal(conf, &homeAsset)
-	homeAsset.Type = args[1]
-	homeAsset.Room = args[2]
-	homeAsset.SaveTime = time.Now().String()
-	jsonAsBytes, _ := json.Marshal(homeAsset)
-	stub.PutState(args[0]+"#home", jsonAsBytes)
+	p := argsPatch([]string{"Type", "Room"}, args[1:], false)
+	if _, err := patchHome(stub, args[0], defaultHome, p, ""); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] " + err.Error())
+	}
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Insert chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -356,14 +494,16 @@ func (t *PS) save_home_car_elevator(stub shim.ChaincodeStubInterface, args []str
 		fmt.Println()
 		return nil, errors.New("[Home INSSERT] Incorrect number of arguments. Expecting 3")
 	}
-	conf, _ := stub.GetState(args[0] + "#home")
-	homeAsset := HomeAsset{}
-	json.Unmarshal(conf, &homeAsset)
-	homeAsset.Elevator = args[1]
-	homeAsset.Parking = args[2]
-	homeAsset.SaveTime = time.Now().String()
-	jsonAsBytes, _ := json.Marshal(homeAsset)
-	stub.PutState(args[0]+"#home", jsonAsBytes)
+	p := argsPatch([]string{"Elevator", "Parking"}, args[1:], false)
+	if _, err := patchHome(stub, args[0], defaultHome, p, ""); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] " + err.Error())
+	}
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Insert chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -372,17 +512,16 @@ func (t *PS) save_home_car_elevator(stub shim.ChaincodeStubInterface, args []str
 }
 
 func (t *PS) modify_home_address(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 6 {
+	if len(args) != 6 && len(args) != 7 {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Home Change >>>>")
-		fmt.Println("               Incorrect number of arguments. Expecting 6")
+		fmt.Println("            Incorrect number of arguments. Expecting 6 or 7")
 		fmt.Println("=======================================================================")
 		fmt.Println()
-		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 6")
+		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 6 or 7")
 	}
-	confUser, _ := stub.GetState(args[0] + "#home")
-	if confUser == nil {
+	if !existState(stub, homeKey(args[0], defaultHome)) {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Home Change >>>>")
@@ -391,26 +530,16 @@ func (t *PS) modify_home_address(stub shim.ChaincodeStubInterface, args []string
 		fmt.Println()
 		return nil, errors.New("[Home CHANGE] Not exist Home")
 	}
-	homeAsset := HomeAsset{}
-	json.Unmarshal(confUser, &homeAsset)
-	if args[1] != "none" {
-		homeAsset.State = args[1]
-	}
-	if args[2] != "none" {
-		homeAsset.City = args[2]
-	}
-	if args[3] != "none" {
-		homeAsset.Street = args[3]
-	}
-	if args[4] != "none" {
-		homeAsset.Adt = args[4]
-	}
-	if args[5] != "none" {
-		homeAsset.Code = args[5]
+	p := argsPatch([]string{"State", "City", "Street", "Adt", "Code"}, args[1:], true)
+	if _, err := patchHome(stub, args[0], defaultHome, p, optArg(args, 6)); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Change >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home CHANGE] " + err.Error())
 	}
-	homeAsset.SaveTime = time.Now().String()
-	jsonAsBytes, _ := json.Marshal(homeAsset)
-	stub.PutState(args[0]+"#home", jsonAsBytes)
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Modify chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -419,17 +548,16 @@ func (t *PS) modify_home_address(stub shim.ChaincodeStubInterface, args []string
 }
 
 func (t *PS) modify_home_room(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 3 {
+	if len(args) != 3 && len(args) != 4 {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Home Change >>>>")
-		fmt.Println("               Incorrect number of arguments. Expecting 3")
+		fmt.Println("            Incorrect number of arguments. Expecting 3 or 4")
 		fmt.Println("=======================================================================")
 		fmt.Println()
-		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 3")
+		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 3 or 4")
 	}
-	confUser, _ := stub.GetState(args[0] + "#home")
-	if confUser == nil {
+	if !existState(stub, homeKey(args[0], defaultHome)) {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Home Change >>>>")
@@ -438,17 +566,16 @@ func (t *PS) modify_home_room(stub shim.ChaincodeStubInterface, args []string) (
 		fmt.Println()
 		return nil, errors.New("[Home CHANGE] Not exist Home")
 	}
-	homeAsset := HomeAsset{}
-	json.Unmarshal(confUser, &homeAsset)
-	if args[1] != "none" {
-		homeAsset.Type = args[1]
-	}
-	if args[2] != "none" {
-		homeAsset.Room = args[2]
+	p := argsPatch([]string{"Type", "Room"}, args[1:], true)
+	if _, err := patchHome(stub, args[0], defaultHome, p, optArg(args, 3)); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Change >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home CHANGE] " + err.Error())
 	}
-	homeAsset.SaveTime = time.Now().String()
-	jsonAsBytes, _ := json.Marshal(homeAsset)
-	stub.PutState(args[0]+"#home", jsonAsBytes)
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Modify chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -457,17 +584,16 @@ func (t *PS) modify_home_room(stub shim.ChaincodeStubInterface, args []string) (
 }
 
 func (t *PS) modify_home_car_elevator(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 3 {
+	if len(args) != 3 && len(args) != 4 {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Home Change >>>>")
-		fmt.Println("               Incorrect number of arguments. Expecting 3")
+		fmt.Println("            Incorrect number of arguments. Expecting 3 or 4")
 		fmt.Println("=======================================================================")
 		fmt.Println()
-		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 3")
+		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 3 or 4")
 	}
-	confUser, _ := stub.GetState(args[0] + "#home")
-	if confUser == nil {
+	if !existState(stub, homeKey(args[0], defaultHome)) {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Home Change >>>>")
@@ -476,17 +602,16 @@ func (t *PS) modify_home_car_elevator(stub shim.ChaincodeStubInterface, args []s
 		fmt.Println()
 		return nil, errors.New("[Home CHANGE] Not exist Home")
 	}
-	homeAsset := HomeAsset{}
-	json.Unmarshal(confUser, &homeAsset)
-	if args[1] != "none" {
-		homeAsset.Elevator = args[1]
-	}
-	if args[2] != "none" {
-		homeAsset.Parking = args[2]
+	p := argsPatch([]string{"Elevator", "Parking"}, args[1:], true)
+	if _, err := patchHome(stub, args[0], defaultHome, p, optArg(args, 3)); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Change >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home CHANGE] " + err.Error())
 	}
-	homeAsset.SaveTime = time.Now().String()
-	jsonAsBytes, _ := json.Marshal(homeAsset)
-	stub.PutState(args[0]+"#home", jsonAsBytes)
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Modify chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -504,6 +629,15 @@ func (t *PS) delete_house(stub shim.ChaincodeStubInterface, args []string) ([]by
 		fmt.Println()
 		return nil, errors.New("[Home DELETE] Incorrect number of arguments. Expecting 1")
 	}
+	if !selfOrAdmin(stub, args[0]) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Delete >>>>")
+		fmt.Println("                              Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home DELETE] Not allowed caller")
+	}
 	userID := args[0] + "#home"
 	conf, _ := stub.GetState(userID)
 	if conf == nil {
@@ -515,7 +649,7 @@ func (t *PS) delete_house(stub shim.ChaincodeStubInterface, args []string) ([]by
 		fmt.Println()
 		return nil, errors.New("[Home DELETE] Not exist Home")
 	}
-	stub.DelState(userID)
+	deleteHome(stub, args[0], defaultHome)
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Delete chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -524,25 +658,108 @@ func (t *PS) delete_house(stub shim.ChaincodeStubInterface, args []string) ([]by
 }
 
 func (t *PS) save_tran(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 8 {
+	if len(args) < 8 || len(args) > 10 {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Trade Insert >>>>")
-		fmt.Println("                Incorrect number of arguments. Expecting 8")
+		fmt.Println("            Incorrect number of arguments. Expecting 8, 9 or 10")
 		fmt.Println("=======================================================================")
 		fmt.Println()
-		return nil, errors.New("[TRADE INSSERT] Incorrect number of arguments. Expecting 8")
+		return nil, errors.New("[TRADE INSSERT] Incorrect number of arguments. Expecting 8, 9 or 10")
 	}
 	psid := args[0]
 	psnick := args[1]
-	csid := args[2]
+	csid := registerUser(stub, args[2])
 	ts := args[3]
 	te := args[4]
 	tc := args[5]
 	ta := args[6]
 	th := args[7]
 
+	if userID(stub, callerAttr(stub, "email")) != csid {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Trade Insert >>>>")
+		fmt.Println("                         Caller is not the consumer")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[TRADE INSSERT] Caller is not the consumer")
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[TRADE INSSERT] " + err.Error())
+	}
+	start, err1 := parseDate(ts)
+	end, err2 := parseDate(te)
+	if err1 != nil || err2 != nil || end.Before(start) {
+		return nil, errors.New("[TRADE INSSERT] Invalid TS/TE: " + ts + "/" + te)
+	}
+	if start.Before(now) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Trade Insert >>>>")
+		fmt.Println("                            Backdated booking")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[TRADE INSSERT] TS is before the transaction time: " + ts)
+	}
+
+	confUser, _ := stub.GetState(psid)
+	if confUser == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Trade Insert >>>>")
+		fmt.Println("                               Not exist Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[TRADE INSSERT] Not exist Petsitter")
+	}
+	petsitter := Petsitter{}
+	json.Unmarshal(confUser, &petsitter)
+	if !petsitterActive(petsitter) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Trade Insert >>>>")
+		fmt.Println("                          Not available Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[TRADE INSSERT] Not available Petsitter: " + petsitter.Status)
+	}
+	var hid string
+	if len(args) >= 9 && args[8] != "" {
+		hid = args[8]
+		if !existState(stub, homeKey(psid, hid)) {
+			fmt.Println()
+			fmt.Println("=======================================================================")
+			fmt.Println("                           <<<< Trade Insert >>>>")
+			fmt.Println("                               Not exist Home")
+			fmt.Println("=======================================================================")
+			fmt.Println()
+			return nil, errors.New("[TRADE INSSERT] Not exist Home: " + hid)
+		}
+	}
+
+	// 멱등 키가 없으면 같은 인자의 재요청을 중복으로 본다
+	// 키가 가리키는 거래가 취소되었으면 같은 키로 다시 예약할 수 있다
+	idem := strings.Join(args[3:8], "#") + "#" + hid
+	if len(args) == 10 && args[9] != "" {
+		idem = args[9]
+	}
+	confIdem, _ := stub.GetState(idemKey(psid, csid, idem))
+	if confIdem != nil && !tradeCancelled(stub, tradeKey(psid, csid, string(confIdem))) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Trade Insert >>>>")
+		fmt.Println("                           Duplicate submission")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[TRADE INSSERT] Duplicate submission: " + string(confIdem))
+	}
+	tid := tradeID(stub)
+	tkey := tradeKey(psid, csid, tid)
+
 	tradeRec := TradeRec{}
+	tradeRec.TradeID = tid
 	tradeRec.PSID = psid
 	tradeRec.PSNickname = psnick
 	tradeRec.CSID = csid
@@ -551,23 +768,31 @@ func (t *PS) save_tran(stub shim.ChaincodeStubInterface, args []string) ([]byte,
 	tradeRec.TC = tc
 	tradeRec.TA = ta
 	tradeRec.TH = th
-	jsonAsBytes, _ := json.Marshal(tradeRec)
-	stub.PutState(psid+"#"+csid+"#"+tc, jsonAsBytes)
-
-	var tstr string
-	conft, _ := stub.GetState(psid + "#t")
-	json.Unmarshal(conft, &tstr)
-	if tstr == "" {
-		tstr = "/"
-	}
-	tstr = tstr + psid + "#" + csid + "#" + tc + "/"
-	tbyte, _ := json.Marshal(tstr)
-	stub.PutState(psid+"#t", tbyte)
+	tradeRec.Status = "booked"
+	tradeRec.HomeID = hid
+	key, err := piiKey(stub)
+	if err == nil {
+		err = encryptFields(stub, key, tkey, tradePII(&tradeRec))
+	}
+	if err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Trade Insert >>>>")
+		fmt.Println("                           Field encryption error")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[TRADE INSSERT] " + err.Error())
+	}
+	jsonAsBytes, _ := marshalDoc(&tradeRec)
+	stub.PutState(tkey, jsonAsBytes)
+	stub.PutState(idemKey(psid, csid, idem), []byte(tid))
+	appendIndex(stub, psid+"#t", tkey)
+	appendIndex(stub, consumerIndexKey(csid), tkey)
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Save Transaction chaincode >>>>")
 	fmt.Println("======================================================================")
 
-	return nil, nil
+	return []byte(tid), nil
 }
 
 func (t *PS) read_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
@@ -597,7 +822,14 @@ func (t *PS) read_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]
 	fmt.Println("                      Reading success, ID: " + key)
 	fmt.Println("=======================================================================")
 	fmt.Println()
-	return valAsbytes, nil
+	encKey, _ := piiKey(stub)
+	if encKey == nil {
+		return valAsbytes, nil
+	}
+	petsitter := Petsitter{}
+	json.Unmarshal(valAsbytes, &petsitter)
+	decryptFields(encKey, key, petsitterPII(&petsitter))
+	return json.Marshal(petsitter)
 }
 
 func (t *PS) read_house(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
@@ -627,17 +859,28 @@ func (t *PS) read_house(stub shim.ChaincodeStubInterface, args []string) ([]byte
 	fmt.Println("                      Reading success, ID: " + args[0])
 	fmt.Println("=======================================================================")
 	fmt.Println()
-	return valAsbytes, nil
+	homeAsset := HomeAsset{}
+	json.Unmarshal(valAsbytes, &homeAsset)
+	return json.Marshal(publicHome(homeAsset))
 }
+
+// 펫시터 ID [, 시작일, 종료일, 상태, 고객 ID, 최소 금액, 최대 금액] (조건이 없으면 "")
 func (t *PS) search_tran(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 1 {
+	if len(args) != 1 && len(args) != 7 {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Trade Search >>>>")
-		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("              Incorrect number of arguments. Expecting 1 or 7")
 		fmt.Println("=======================================================================")
 		fmt.Println()
-		return nil, errors.New("[TRADE SEARCH] Incorrect number of arguments. Expecting 1")
+		return nil, errors.New("[TRADE SEARCH] Incorrect number of arguments. Expecting 1 or 7")
+	}
+	f := tradeFilter{}
+	if len(args) == 7 {
+		f = tradeFilter{From: args[1], To: args[2], Status: args[3], CSID: args[4], MinTA: args[5], MaxTA: args[6]}
+		if err := f.validate(); err != nil {
+			return nil, errors.New("[TRADE SEARCH] " + err.Error())
+		}
 	}
 	var tt string
 	ttt, _ := stub.GetState(args[0] + "#t")
@@ -652,19 +895,13 @@ func (t *PS) search_tran(stub shim.ChaincodeStubInterface, args []string) ([]byt
 		return []byte("None"), errors.New("[TRADE SEARCH] Not exist transaction")
 	}
 
-	var start, end int
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[TRADE SEARCH] " + err.Error())
+	}
 	var ret string
-	tra := TradeRec{}
-	for i, v := range tt {
-		if v == 38 {
-			end = i
-			if tt[start:end] != "" {
-				valAsbytes, _ := stub.GetState(tt[start:end])
-				json.Unmarshal(valAsbytes, &tra)
-				ret = ret + "0" + "," + tra.PSID + "," + tra.PSNickname + "," + tra.CSID + "," + tra.TS + "," + tra.TE + "," + tra.TC + "," + tra.TA + "," + tra.TH + "&"
-			}
-			start = end + 1
-		}
+	for _, tra := range filterTrades(stub, tt, f, now) {
+		ret = ret + "0" + "," + tra.PSID + "," + tra.PSNickname + "," + tra.CSID + "," + tra.TS + "," + tra.TE + "," + tra.TC + "," + tra.TA + "," + tra.TH + "&"
 	}
 
 	fmt.Println()
@@ -676,6 +913,17 @@ func (t *PS) search_tran(stub shim.ChaincodeStubInterface, args []string) ([]byt
 	return []byte(ret), nil
 }
 
+type rankedRet struct { // search_bytotal result ordered by rating
+	rating float64
+	ret    string
+}
+
+type byRating []rankedRet
+
+func (a byRating) Len() int           { return len(a) }
+func (a byRating) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byRating) Less(i, j int) bool { return a[i].rating > a[j].rating }
+
 // 지역, 총마리수, 대형견, 중형견, 소형견, 체크인, 체크아웃
 func (t *PS) search_bytotal(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	if len(args) != 7 {
@@ -689,17 +937,19 @@ func (t *PS) search_bytotal(stub shim.ChaincodeStubInterface, args []string) ([]
 	}
 	var start, end int
 	var ret string
+	var ranked []rankedRet
 	srt := Petsitter{}
-	srth := HomeAsset{}
+	regions := loadRegions(stub)
+	state := canonicalState(regions, args[0])
 	for i, v := range CCstr {
 		if v == 47 {
 			end = i
 			if CCstr[start:end] != "" {
 				ps, _ := stub.GetState(CCstr[start:end])
-				psh, _ := stub.GetState(CCstr[start:end] + "#home")
+				srt = Petsitter{}
 				json.Unmarshal(ps, &srt)
-				json.Unmarshal(psh, &srth)
-				if srth.State == args[0] {
+				srth, found := regionHome(stub, CCstr[start:end], state, regions)
+				if found && petsitterActive(srt) {
 					N1, _ := strconv.Atoi(srt.TotalNum)
 					N2, _ := strconv.Atoi(args[1])
 					if N1 >= N2 {
@@ -734,7 +984,8 @@ func (t *PS) search_bytotal(stub shim.ChaincodeStubInterface, args []string) ([]
 													ret3 := srth.Adt + "," + srth.Code + ","
 													ret4 := srth.Type + "," + srth.Room + ","
 													ret5 := srth.Elevator + "," + srth.Parking + "," + srth.SaveTime
-													ret = ret + ret1 + ret2 + ret3 + ret4 + ret5 + "/"
+													rating, _ := strconv.ParseFloat(srt.Rating, 64)
+													ranked = append(ranked, rankedRet{rating, ret1 + ret2 + ret3 + ret4 + ret5 + "/"})
 												}
 											} else {
 												return []byte("Error Except date"), errors.New("Error Except date")
@@ -750,6 +1001,10 @@ func (t *PS) search_bytotal(stub shim.ChaincodeStubInterface, args []string) ([]
 			start = end + 1
 		}
 	}
+	sort.Stable(byRating(ranked))
+	for _, v := range ranked {
+		ret = ret + v.ret
+	}
 	if ret == "" {
 		return []byte("None"), nil
 	}
@@ -766,21 +1021,16 @@ func (t *PS) save_home(stub shim.ChaincodeStubInterface, args []string) ([]byte,
 		fmt.Println()
 		return nil, errors.New("[Home INSSERT] Incorrect number of arguments. Expecting 10")
 	}
-	conf, _ := stub.GetState(args[0] + "#home")
-	homeAsset := HomeAsset{}
-	json.Unmarshal(conf, &homeAsset)
-	homeAsset.State = args[1]
-	homeAsset.City = args[2]
-	homeAsset.Street = args[3]
-	homeAsset.Adt = args[4]
-	homeAsset.Code = args[5]
-	homeAsset.Type = args[6]
-	homeAsset.Room = args[7]
-	homeAsset.Elevator = args[8]
-	homeAsset.Parking = args[9]
-	homeAsset.SaveTime = time.Now().String()
-	jsonAsBytes, _ := json.Marshal(homeAsset)
-	stub.PutState(args[0]+"#home", jsonAsBytes)
+	p := argsPatch([]string{"State", "City", "Street", "Adt", "Code", "Type", "Room", "Elevator", "Parking"}, args[1:], false)
+	if _, err := patchHome(stub, args[0], defaultHome, p, ""); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] " + err.Error())
+	}
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Insert chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -789,17 +1039,16 @@ func (t *PS) save_home(stub shim.ChaincodeStubInterface, args []string) ([]byte,
 }
 
 func (t *PS) modify_home(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
-	if len(args) != 10 {
+	if len(args) != 10 && len(args) != 11 {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Home Change >>>>")
-		fmt.Println("               Incorrect number of arguments. Expecting 10")
+		fmt.Println("            Incorrect number of arguments. Expecting 10 or 11")
 		fmt.Println("=======================================================================")
 		fmt.Println()
-		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 10")
+		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 10 or 11")
 	}
-	confUser, _ := stub.GetState(args[0] + "#home")
-	if confUser == nil {
+	if !existState(stub, homeKey(args[0], defaultHome)) {
 		fmt.Println()
 		fmt.Println("=======================================================================")
 		fmt.Println("                           <<<< Home Change >>>>")
@@ -808,38 +1057,16 @@ func (t *PS) modify_home(stub shim.ChaincodeStubInterface, args []string) ([]byt
 		fmt.Println()
 		return nil, errors.New("[Home CHANGE] Not exist Home")
 	}
-	homeAsset := HomeAsset{}
-	json.Unmarshal(confUser, &homeAsset)
-	if args[1] != "none" {
-		homeAsset.State = args[1]
-	}
-	if args[2] != "none" {
-		homeAsset.City = args[2]
-	}
-	if args[3] != "none" {
-		homeAsset.Street = args[3]
-	}
-	if args[4] != "none" {
-		homeAsset.Adt = args[4]
-	}
-	if args[5] != "none" {
-		homeAsset.Code = args[5]
-	}
-	if args[6] != "none" {
-		homeAsset.Type = args[6]
-	}
-	if args[7] != "none" {
-		homeAsset.Room = args[7]
-	}
-	if args[8] != "none" {
-		homeAsset.Elevator = args[8]
-	}
-	if args[9] != "none" {
-		homeAsset.Parking = args[9]
+	p := argsPatch([]string{"State", "City", "Street", "Adt", "Code", "Type", "Room", "Elevator", "Parking"}, args[1:], true)
+	if _, err := patchHome(stub, args[0], defaultHome, p, optArg(args, 10)); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Change >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home CHANGE] " + err.Error())
 	}
-	homeAsset.SaveTime = time.Now().String()
-	jsonAsBytes, _ := json.Marshal(homeAsset)
-	stub.PutState(args[0]+"#home", jsonAsBytes)
 	fmt.Println("============================<< SUCCESS >>=============================")
 	fmt.Println("                  <<<< Home Modify chaincode >>>>")
 	fmt.Println("======================================================================")
@@ -861,17 +1088,18 @@ func (t *PS) search_byregion(stub shim.ChaincodeStubInterface, args []string) ([
 	var start, end int
 	var ret string
 	srt := Petsitter{}
-	srth := HomeAsset{}
+	regions := loadRegions(stub)
+	state := canonicalState(regions, args[0])
 
 	for i, v := range CCstr {
 		if v == 47 {
 			end = i
 			if CCstr[start:end] != "" {
 				ps, _ := stub.GetState(CCstr[start:end])
-				psh, _ := stub.GetState(CCstr[start:end] + "#home")
+				srt = Petsitter{}
 				json.Unmarshal(ps, &srt)
-				json.Unmarshal(psh, &srth)
-				if srth.State == args[0] {
+				srth, found := regionHome(stub, CCstr[start:end], state, regions)
+				if found && petsitterActive(srt) {
 					ret1 := CCstr[start:end] + "," + srt.Nickname + "," + srt.CostL + "," + srt.CostM + "," + srt.CostS + "," + srt.Start + "," + srt.End + "," + srt.Except + "," + srt.TotalNum + ","
 					ret2 := srt.NumL + "," + srt.NumM + "," + srt.NumS + "," + srt.Home + "," + srt.HomeInfo + "," + srt.SaveTime + "?" + srth.State + "," + srth.City + "," + srth.Street + ","
 					ret3 := srth.Adt + "," + srth.Code + ","
@@ -889,3 +1117,22 @@ func (t *PS) search_byregion(stub shim.ChaincodeStubInterface, args []string) ([
 	}
 	return []byte(ret), nil
 }
+
+// 인증서 속성 (role, email)
+func callerAttr(stub shim.ChaincodeStubInterface, name string) string {
+	val, err := stub.ReadCertAttribute(name)
+	if err != nil {
+		return ""
+	}
+	return string(val)
+}
+
+func hasRole(stub shim.ChaincodeStubInterface, role string) bool {
+	return callerAttr(stub, "role") == role
+}
+
+// 본인(인증서 email 의 ID) 또는 관리자
+func selfOrAdmin(stub shim.ChaincodeStubInterface, id string) bool {
+	email := callerAttr(stub, "email")
+	return email != "" && userID(stub, email) == id || hasRole(stub, "admin")
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 운영자용 통계 (관리자 조회)
+
+type StateSitters struct { // analytics_sitters result
+	Code    string // Region code ("" if not in the region table)
+	State   string
+	Sitters string // Active petsitters with a home in the state
+}
+
+type PriceStats struct { // analytics_prices result
+	Class   string // L, M, S
+	Sitters string // Active petsitters offering the class
+	AvgCost string
+	MinCost string
+	MaxCost string
+}
+
+type BookingVolume struct { // analytics_bookings result
+	Month     string // YYYYMM (TS)
+	Trades    string
+	Cancelled string
+	Amount    string // Sum of TA, cancelled trades excluded
+}
+
+func (t *PS) analytics_sitters(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if err := analyticsCheck(stub, args, 0); err != nil {
+		return nil, err
+	}
+	regions := loadRegions(stub)
+	active := map[string]bool{}
+	states := map[string]map[string]bool{}
+	err := scanAll(stub, func(key string, val []byte) {
+		switch docTypeOf(key) {
+		case docPetsitter:
+			petsitter := Petsitter{}
+			json.Unmarshal(val, &petsitter)
+			active[key] = petsitterActive(petsitter)
+		case docHome:
+			homeAsset := HomeAsset{}
+			json.Unmarshal(val, &homeAsset)
+			state := canonicalState(regions, homeAsset.State)
+			if states[state] == nil {
+				states[state] = map[string]bool{}
+			}
+			states[state][strings.Split(key, "#")[0]] = true
+		}
+	})
+	if err != nil {
+		return nil, errors.New("[ANALYTICS] " + err.Error())
+	}
+	ret := []StateSitters{}
+	for state, ids := range states {
+		n := 0
+		for id := range ids {
+			if active[id] {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		region, _ := findRegion(regions, state)
+		ret = append(ret, StateSitters{region.Code, state, strconv.Itoa(n)})
+	}
+	sort.Sort(byStateCode(ret))
+	return json.Marshal(ret)
+}
+
+func (t *PS) analytics_prices(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if err := analyticsCheck(stub, args, 0); err != nil {
+		return nil, err
+	}
+	costs := map[string][]float64{}
+	err := scanAll(stub, func(key string, val []byte) {
+		if docTypeOf(key) != docPetsitter {
+			return
+		}
+		petsitter := Petsitter{}
+		json.Unmarshal(val, &petsitter)
+		if !petsitterActive(petsitter) {
+			return
+		}
+		for class, cost := range map[string]string{"L": petsitter.CostL, "M": petsitter.CostM, "S": petsitter.CostS} {
+			if v := parseAmount(cost); v > 0 {
+				costs[class] = append(costs[class], v)
+			}
+		}
+	})
+	if err != nil {
+		return nil, errors.New("[ANALYTICS] " + err.Error())
+	}
+	ret := []PriceStats{}
+	for _, class := range []string{"L", "M", "S"} {
+		v := costs[class]
+		stats := PriceStats{class, strconv.Itoa(len(v)), "0", "0", "0"}
+		if len(v) > 0 {
+			sort.Float64s(v)
+			var sum float64
+			for _, c := range v {
+				sum += c
+			}
+			stats.AvgCost = ratio(sum, len(v))
+			stats.MinCost = strconv.FormatFloat(v[0], 'f', 0, 64)
+			stats.MaxCost = strconv.FormatFloat(v[len(v)-1], 'f', 0, 64)
+		}
+		ret = append(ret, stats)
+	}
+	return json.Marshal(ret)
+}
+
+// 시작일, 종료일 (조건이 없으면 "")
+func (t *PS) analytics_bookings(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if err := analyticsCheck(stub, args, 2); err != nil {
+		return nil, err
+	}
+	f := tradeFilter{From: args[0], To: args[1]}
+	if err := f.validate(); err != nil {
+		return nil, errors.New("[ANALYTICS] " + err.Error())
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[ANALYTICS] " + err.Error())
+	}
+	months := map[string]*[3]float64{}
+	err = scanAll(stub, func(key string, val []byte) {
+		if docTypeOf(key) != docTrade {
+			return
+		}
+		tradeRec := TradeRec{}
+		json.Unmarshal(val, &tradeRec)
+		if len(tradeRec.TS) < 6 || !f.match(tradeRec, now) {
+			return
+		}
+		m := months[tradeRec.TS[:6]]
+		if m == nil {
+			m = &[3]float64{}
+			months[tradeRec.TS[:6]] = m
+		}
+		m[0]++
+		if tradeRec.Status == "cancelled" {
+			m[1]++
+		} else {
+			m[2] += parseAmount(tradeRec.TA)
+		}
+	})
+	if err != nil {
+		return nil, errors.New("[ANALYTICS] " + err.Error())
+	}
+	var keys []string
+	for month := range months {
+		keys = append(keys, month)
+	}
+	sort.Strings(keys)
+	ret := []BookingVolume{}
+	for _, month := range keys {
+		m := months[month]
+		ret = append(ret, BookingVolume{month, strconv.FormatFloat(m[0], 'f', 0, 64), strconv.FormatFloat(m[1], 'f', 0, 64), strconv.FormatFloat(m[2], 'f', 0, 64)})
+	}
+	return json.Marshal(ret)
+}
+
+func analyticsCheck(stub shim.ChaincodeStubInterface, args []string, n int) error {
+	if len(args) != n {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                            <<<< Analytics >>>>")
+		fmt.Printf("                Incorrect number of arguments. Expecting %d\n", n)
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return fmt.Errorf("[ANALYTICS] Incorrect number of arguments. Expecting %d", n)
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                            <<<< Analytics >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return errors.New("[ANALYTICS] Caller is not an admin")
+	}
+	return nil
+}
+
+// 전체 상태를 순서대로 처리
+func scanAll(stub shim.ChaincodeStubInterface, fn func(key string, val []byte)) error {
+	iter, err := stub.RangeQueryState(firstKey, lastKey)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		key, val, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		fn(key, val)
+	}
+	return nil
+}
+
+type byStateCode []StateSitters
+
+func (a byStateCode) Len() int      { return len(a) }
+func (a byStateCode) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byStateCode) Less(i, j int) bool {
+	if a[i].Code != a[j].Code {
+		return a[i].Code < a[j].Code
+	}
+	return a[i].State < a[j].State
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAnalyticsAdminOnly(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.trade(t, psid, "cs@example.com", "20240520", "20240525")
+
+	for _, fn := range []string{"analytics_sitters", "analytics_prices"} {
+		s.as("ps@example.com")
+		_, err := s.query(fn)
+		expectErr(t, err, fn+" by a non-admin")
+	}
+	s.as("ps@example.com")
+	_, err := s.query("analytics_bookings", "", "")
+	expectErr(t, err, "analytics_bookings by a non-admin")
+
+	s.admin()
+	ret, err := s.query("analytics_bookings", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var volume []BookingVolume
+	json.Unmarshal(ret, &volume)
+	if len(volume) != 1 || volume[0].Month != "202405" || volume[0].Amount != "100000" {
+		t.Fatalf("bookings = %+v", volume)
+	}
+}
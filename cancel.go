@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+type CancelPolicy struct { // Cancellation policy (KEY: User ID#policy)
+	Type          string // flexible, moderate, strict or custom
+	Tiers         string // Refund tiers "days:percent/days:percent/..."
+	SaveTime      string
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
+}
+
+type CancelRec struct { // Cancellation record (KEY: PSID#CSID#거래 ID#cancel)
+	Policy        string // Applied policy type
+	Tiers         string // Applied refund tiers
+	DaysBefore    string // Days left before TS
+	Percent       string // Refund percent
+	Refund        string // Amount returned to the consumer
+	Penalty       string // Amount kept by the petsitter
+	CancelTime    string
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
+}
+
+type policyTier struct {
+	days    int
+	percent int
+}
+
+type byDays []policyTier
+
+func (a byDays) Len() int           { return len(a) }
+func (a byDays) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byDays) Less(i, j int) bool { return a[i].days > a[j].days }
+
+// 기본 환불 규정 (일수:환불율)
+var policyPresets = map[string]string{
+	"flexible": "1:100/0:0",
+	"moderate": "5:100/1:50/0:0",
+	"strict":   "14:100/7:50/0:0",
+}
+
+func (t *PS) set_cancel_policy(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Policy Insert >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Policy INSSERT] Incorrect number of arguments. Expecting 3")
+	}
+	if userID(stub, callerAttr(stub, "email")) != args[0] {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Policy Insert >>>>")
+		fmt.Println("                           Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Policy INSSERT] Not allowed caller")
+	}
+	conf, _ := stub.GetState(args[0])
+	if conf == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Policy Insert >>>>")
+		fmt.Println("                               Not exist Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Policy INSSERT] Not exist Petsitter")
+	}
+	policy := CancelPolicy{}
+	policy.Type = args[1]
+	if policy.Type == "custom" {
+		policy.Tiers = args[2]
+	} else {
+		policy.Tiers = policyPresets[policy.Type]
+	}
+	if _, err := parsePolicyTiers(policy.Tiers); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Policy Insert >>>>")
+		fmt.Println("                              Invalid policy: " + policy.Type)
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Policy INSSERT] Invalid policy: " + err.Error())
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Policy INSSERT] " + err.Error())
+	}
+	policy.SaveTime = now.String()
+	jsonAsBytes, _ := marshalDoc(&policy)
+	stub.PutState(args[0]+"#policy", jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Policy Insert chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+func (t *PS) read_cancel_policy(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Policy Read >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Policy QUERY] Incorrect number of arguments. Expecting 1")
+	}
+	return json.Marshal(readCancelPolicy(stub, args[0]))
+}
+
+// PSID, CSID, 거래 ID
+func (t *PS) cancel_booking(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Cancel >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking CANCEL] Incorrect number of arguments. Expecting 3")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	valAsbytes, _ := stub.GetState(key)
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Cancel >>>>")
+		fmt.Println("                           Not exist transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking CANCEL] Not exist transaction")
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	caller := userID(stub, callerAttr(stub, "email"))
+	if caller != tradeRec.CSID && caller != tradeRec.PSID {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Cancel >>>>")
+		fmt.Println("                         Not a party of the trade")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking CANCEL] Not a party of the trade")
+	}
+	if tradeRec.Status == "cancelled" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Cancel >>>>")
+		fmt.Println("                          Already cancelled booking")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking CANCEL] Already cancelled booking")
+	}
+	if tradeRec.Status == "disputed" || tradeRec.Status == "resolved" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Cancel >>>>")
+		fmt.Println("                            Disputed booking")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking CANCEL] Disputed booking")
+	}
+
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Booking CANCEL] " + err.Error())
+	}
+	start, err := parseDate(tradeRec.TS)
+	if err != nil {
+		return nil, errors.New("[Booking CANCEL] Invalid TS: " + tradeRec.TS)
+	}
+	if !now.Before(start) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Cancel >>>>")
+		fmt.Println("                          Already started booking")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking CANCEL] Already started booking")
+	}
+	amount, err := strconv.Atoi(tradeRec.TA)
+	if err != nil {
+		return nil, errors.New("[Booking CANCEL] Invalid TA: " + tradeRec.TA)
+	}
+
+	policy := readCancelPolicy(stub, tradeRec.PSID)
+	tiers, err := parsePolicyTiers(policy.Tiers)
+	if err != nil {
+		return nil, errors.New("[Booking CANCEL] Invalid policy: " + err.Error())
+	}
+	days := int(start.Sub(now) / (24 * time.Hour))
+	percent := refundPercent(tiers, days)
+	refund := amount * percent / 100
+
+	cancelRec := CancelRec{}
+	cancelRec.Policy = policy.Type
+	cancelRec.Tiers = policy.Tiers
+	cancelRec.DaysBefore = strconv.Itoa(days)
+	cancelRec.Percent = strconv.Itoa(percent)
+	cancelRec.Refund = strconv.Itoa(refund)
+	cancelRec.Penalty = strconv.Itoa(amount - refund)
+	cancelRec.CancelTime = now.String()
+	jsonAsBytes, _ := marshalDoc(&cancelRec)
+	stub.PutState(key+"#cancel", jsonAsBytes)
+
+	tradeRec.Status = "cancelled"
+	tradeRec.Refund = cancelRec.Refund
+	tradeRec.Payout = cancelRec.Penalty
+	appendHistory(&tradeRec, "cancelled:"+cancelRec.Refund+":"+cancelRec.Penalty+"@"+now.Format("20060102"))
+	jsonAsBytes, _ = marshalDoc(&tradeRec)
+	stub.PutState(key, jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Booking Cancel chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return json.Marshal(cancelRec)
+}
+
+func readCancelPolicy(stub shim.ChaincodeStubInterface, psid string) CancelPolicy {
+	policy := CancelPolicy{}
+	valAsbytes, _ := stub.GetState(psid + "#policy")
+	if valAsbytes == nil {
+		policy.Type = "flexible"
+		policy.Tiers = policyPresets["flexible"]
+		return policy
+	}
+	json.Unmarshal(valAsbytes, &policy)
+	return policy
+}
+
+// "days:percent/days:percent/..." -> 일수 내림차순
+func parsePolicyTiers(s string) ([]policyTier, error) {
+	var tiers []policyTier
+	for _, v := range strings.Split(s, "/") {
+		if v == "" {
+			continue
+		}
+		kv := strings.Split(v, ":")
+		if len(kv) != 2 {
+			return nil, errors.New("bad tier " + v)
+		}
+		days, err := strconv.Atoi(kv[0])
+		if err != nil || days < 0 {
+			return nil, errors.New("bad tier days " + v)
+		}
+		percent, err := strconv.Atoi(kv[1])
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, errors.New("bad tier percent " + v)
+		}
+		tiers = append(tiers, policyTier{days, percent})
+	}
+	if len(tiers) == 0 {
+		return nil, errors.New("no tiers")
+	}
+	sort.Sort(byDays(tiers))
+	return tiers, nil
+}
+
+func refundPercent(tiers []policyTier, days int) int {
+	for _, tier := range tiers {
+		if days >= tier.days {
+			return tier.percent
+		}
+	}
+	return 0
+}
+
+// 트랜잭션 타임스탬프 (모든 피어에서 동일)
+func txTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+// YYYYMMDD
+func parseDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, errors.New("bad date " + s)
+	}
+	return time.Parse("20060102", s[:8])
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSetCancelPolicyCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+
+	s.as("other@example.com")
+	_, err := s.invoke("set_cancel_policy", psid, "strict", "")
+	expectErr(t, err, "policy by another user")
+
+	s.as("ps@example.com")
+	s.mustInvoke(t, "set_cancel_policy", "ps@example.com", "strict", "")
+	policy := readCancelPolicy(s, psid)
+	if policy.Type != "strict" || policy.SaveTime != s.now.String() {
+		t.Fatalf("policy = %+v", policy)
+	}
+}
+
+func TestCancelBookingCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+	csid := userID(s, "cs@example.com")
+
+	s.as("other@example.com")
+	_, err := s.invoke("cancel_booking", psid, csid, tid)
+	expectErr(t, err, "cancel by a third party")
+
+	s.as("cs@example.com")
+	ret := s.mustInvoke(t, "cancel_booking", psid, csid, tid)
+	cancelRec := CancelRec{}
+	json.Unmarshal(ret, &cancelRec)
+	if cancelRec.Refund != "100000" || cancelRec.Penalty != "0" {
+		t.Fatalf("cancel = %+v", cancelRec)
+	}
+}
+
+func TestReadCancelPolicyRevision(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.as("ps@example.com")
+	s.mustInvoke(t, "set_cancel_policy", "ps@example.com", "moderate", "")
+
+	for i := 0; i < 2; i++ {
+		ret, err := s.query("read_cancel_policy", psid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		policy := CancelPolicy{}
+		json.Unmarshal(ret, &policy)
+		if policy.Revision != "1" || policy.Type != "moderate" {
+			t.Fatalf("read %d: policy = %+v", i, policy)
+		}
+	}
+	if stored := readCancelPolicy(s, psid); stored.Revision != "1" {
+		t.Fatalf("stored policy = %+v", stored)
+	}
+}
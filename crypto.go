@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 개인정보 필드 암호화 (AES-256-GCM)
+// 키는 caller metadata({"PIIKey": base64})로 전달한다. Fabric 0.6 에는 transient map 이 없고,
+// metadata 는 블록의 트랜잭션에 함께 기록된다. confidentiality(security.privacy)를 켜지 않으면
+// 블록을 읽을 수 있는 누구나 키를 볼 수 있으므로, 이 경우 암호화는 월드 상태 조회만 막는다.
+// 모든 피어가 같은 결과를 내도록 nonce 는 트랜잭션 ID, 레코드 키, 필드 이름에서 만든다.
+// 추가 인증 데이터는 "레코드 키#필드 이름"이므로 암호문을 다른 레코드나 필드로 옮기면 복호화되지 않는다.
+const encPrefix = "enc:"
+
+type callerMeta struct { // Caller metadata
+	PIIKey string // base64 AES-256 key
+}
+
+// 메타데이터에 키가 없으면 nil (암호화하지 않음)
+func piiKey(stub shim.ChaincodeStubInterface) ([]byte, error) {
+	meta, err := stub.GetCallerMetadata()
+	if err != nil || len(meta) == 0 {
+		return nil, nil
+	}
+	m := callerMeta{}
+	if err := json.Unmarshal(meta, &m); err != nil || m.PIIKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(m.PIIKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("PIIKey must be a base64 encoded 32 byte key")
+	}
+	return key, nil
+}
+
+// 입력 값만 넘긴다. enc: 로 시작하는 값은 암호문으로 오인되므로 받지 않는다.
+func encryptField(stub shim.ChaincodeStubInterface, key []byte, recordKey, field, plaintext string) (string, error) {
+	if strings.HasPrefix(plaintext, encPrefix) {
+		return "", errors.New(field + " must not start with " + encPrefix)
+	}
+	if key == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	seed := sha256.Sum256([]byte(stub.GetTxID() + "#" + recordKey + "#" + field))
+	nonce := seed[:gcm.NonceSize()]
+	sealed := gcm.Seal(nil, nonce, []byte(plaintext), []byte(recordKey+"#"+field))
+	return encPrefix + base64.StdEncoding.EncodeToString(append(nonce, sealed...)), nil
+}
+
+// 키가 없거나 복호화에 실패하면 그대로 반환
+func decryptField(key []byte, recordKey, field, s string) string {
+	if key == nil || !strings.HasPrefix(s, encPrefix) {
+		return s
+	}
+	raw, err := base64.StdEncoding.DecodeString(s[len(encPrefix):])
+	if err != nil {
+		return s
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return s
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil || len(raw) < gcm.NonceSize() {
+		return s
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], []byte(recordKey+"#"+field))
+	if err != nil {
+		return s
+	}
+	return string(plain)
+}
+
+func encryptFields(stub shim.ChaincodeStubInterface, key []byte, recordKey string, fields map[string]*string) error {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := fields[name]
+		enc, err := encryptField(stub, key, recordKey, name, *v)
+		if err != nil {
+			return err
+		}
+		*v = enc
+	}
+	return nil
+}
+
+func decryptFields(key []byte, recordKey string, fields map[string]*string) {
+	for name, v := range fields {
+		*v = decryptField(key, recordKey, name, *v)
+	}
+}
+
+func petsitterPII(petsitter *Petsitter) map[string]*string {
+	return map[string]*string{"Nickname": &petsitter.Nickname, "Home": &petsitter.Home, "HomeInfo": &petsitter.HomeInfo}
+}
+
+func addressPII(addr *HomeAddress) map[string]*string {
+	return map[string]*string{"Street": &addr.Street, "Adt": &addr.Adt, "Code": &addr.Code}
+}
+
+func tradePII(tradeRec *TradeRec) map[string]*string {
+	return map[string]*string{"PSNickname": &tradeRec.PSNickname}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testKeyMeta() []byte {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	return []byte(`{"PIIKey":"` + key + `"}`)
+}
+
+func TestEncryptRejectsPrefix(t *testing.T) {
+	s := newTestStub(t)
+	for _, meta := range [][]byte{nil, testKeyMeta()} {
+		s.meta = meta
+		s.as("ps@example.com")
+		_, err := s.invoke("save_petsitter", "ps@example.com", "enc:forged", "30000", "20000", "10000", "20240101", "20241231", "", "3", "1", "1", "1", "apt", "info")
+		expectErr(t, err, "enc: prefixed nickname")
+	}
+}
+
+func TestPatchKeepsCiphertext(t *testing.T) {
+	s := newTestStub(t)
+	s.meta = testKeyMeta()
+	psid := s.petsitter(t, "ps@example.com")
+	stored := func() Petsitter {
+		petsitter := Petsitter{}
+		conf, _ := s.GetState(psid)
+		json.Unmarshal(conf, &petsitter)
+		return petsitter
+	}
+	nick := stored().Nickname
+	if !strings.HasPrefix(nick, encPrefix) {
+		t.Fatalf("nickname not encrypted: %s", nick)
+	}
+
+	// 키 없이 다른 필드만 변경
+	s.meta = nil
+	s.as("ps@example.com")
+	s.mustInvoke(t, "patch_petsitter", "ps@example.com", `{"CostL":"35000"}`)
+	if got := stored(); got.Nickname != nick || got.CostL != "35000" {
+		t.Fatalf("petsitter = %+v", got)
+	}
+
+	s.meta = testKeyMeta()
+	key, _ := piiKey(s)
+	s.mustInvoke(t, "patch_petsitter", "ps@example.com", `{"Home":"house"}`)
+	got := stored()
+	decryptFields(key, psid, petsitterPII(&got))
+	if got.Nickname != "nick" || got.Home != "house" {
+		t.Fatalf("decrypted petsitter = %+v", got)
+	}
+}
+
+// 암호문을 다른 사용자의 레코드로 옮기면 복호화되지 않는다
+func TestCiphertextBoundToRecord(t *testing.T) {
+	s := newTestStub(t)
+	s.meta = testKeyMeta()
+	a := s.petsitter(t, "a@example.com")
+	b := s.petsitter(t, "b@example.com")
+	key, _ := piiKey(s)
+	stored := func(id string) Petsitter {
+		petsitter := Petsitter{}
+		conf, _ := s.GetState(id)
+		json.Unmarshal(conf, &petsitter)
+		return petsitter
+	}
+
+	copied := stored(b)
+	copied.Nickname = stored(a).Nickname
+	if got := decryptField(key, b, "Nickname", copied.Nickname); got != copied.Nickname {
+		t.Fatalf("copied ciphertext decrypted to %q", got)
+	}
+	if got := decryptField(key, a, "Home", stored(a).Nickname); got == "nick" {
+		t.Fatal("ciphertext decrypted as another field")
+	}
+	if got := decryptField(key, a, "Nickname", stored(a).Nickname); got != "nick" {
+		t.Fatalf("own ciphertext = %q", got)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+type Dispute struct { // Dispute on a trade (KEY: PSID#CSID#거래 ID#dispute)
+	Opener        string // PSID or CSID
+	Reason        string
+	Status        string // open, resolved
+	Evidence      []Evidence
+	Outcome       string // release, refund, split
+	Percent       string // Refund percent
+	Refund        string // Amount returned to the consumer
+	Payout        string // Amount released to the petsitter
+	Arbitrator    string
+	Note          string
+	OpenTime      string
+	ResolveTime   string
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
+}
+
+type Evidence struct {
+	Submitter   string
+	Hash        string // Hash of the evidence file
+	Description string
+	SaveTime    string
+}
+
+// PSID, CSID, 거래 ID, 신청자(호출자 본인), 사유
+func (t *PS) open_dispute(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 5 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Dispute Open >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 5")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute OPEN] Incorrect number of arguments. Expecting 5")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	valAsbytes, _ := stub.GetState(key)
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Dispute Open >>>>")
+		fmt.Println("                           Not exist transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute OPEN] Not exist transaction")
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	opener := userID(stub, callerAttr(stub, "email"))
+	if opener != args[3] || (opener != tradeRec.PSID && opener != tradeRec.CSID) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Dispute Open >>>>")
+		fmt.Println("                         Not a party of the trade")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute OPEN] Not a party of the trade")
+	}
+	// 예약, 수락 상태만 (완료된 거래도 상태는 booked/accepted)
+	if tradeRec.Status != "" && tradeRec.Status != "booked" && tradeRec.Status != "accepted" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Dispute Open >>>>")
+		fmt.Println("                          Not disputable trade")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute OPEN] Not disputable trade: " + tradeRec.Status)
+	}
+	conf, _ := stub.GetState(key + "#dispute")
+	if conf != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Dispute Open >>>>")
+		fmt.Println("                           Already exist Dispute")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute OPEN] Already exist Dispute")
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Dispute OPEN] " + err.Error())
+	}
+
+	dispute := Dispute{}
+	dispute.Opener = opener
+	dispute.Reason = args[4]
+	dispute.Status = "open"
+	dispute.Evidence = []Evidence{}
+	dispute.OpenTime = now.String()
+	jsonAsBytes, _ := marshalDoc(&dispute)
+	stub.PutState(key+"#dispute", jsonAsBytes)
+
+	tradeRec.Status = "disputed"
+	appendHistory(&tradeRec, "disputed:"+opener+"@"+now.Format("20060102"))
+	jsonAsBytes, _ = marshalDoc(&tradeRec)
+	stub.PutState(key, jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Dispute Open chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// PSID, CSID, 거래 ID, 제출자(호출자 본인), 해시, 설명
+func (t *PS) add_dispute_evidence(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 6 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Evidence Insert >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 6")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Evidence INSSERT] Incorrect number of arguments. Expecting 6")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	conf, _ := stub.GetState(key + "#dispute")
+	if conf == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Evidence Insert >>>>")
+		fmt.Println("                              Not exist Dispute")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Evidence INSSERT] Not exist Dispute")
+	}
+	dispute := Dispute{}
+	json.Unmarshal(conf, &dispute)
+	if dispute.Status != "open" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Evidence Insert >>>>")
+		fmt.Println("                          Already resolved Dispute")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Evidence INSSERT] Already resolved Dispute")
+	}
+	submitter := userID(stub, callerAttr(stub, "email"))
+	if submitter != args[3] || (submitter != args[0] && submitter != args[1]) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Evidence Insert >>>>")
+		fmt.Println("                         Not a party of the trade")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Evidence INSSERT] Not a party of the trade")
+	}
+	if args[4] == "" {
+		return nil, errors.New("[Evidence INSSERT] Empty evidence hash")
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Evidence INSSERT] " + err.Error())
+	}
+	dispute.Evidence = append(dispute.Evidence, Evidence{submitter, args[4], args[5], now.String()})
+	jsonAsBytes, _ := marshalDoc(&dispute)
+	stub.PutState(key+"#dispute", jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                 <<<< Evidence Insert chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// PSID, CSID, 거래 ID, 결과(release, refund, split), 환불율, 메모
+func (t *PS) resolve_dispute(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 6 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Dispute Resolve >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 6")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute RESOLVE] Incorrect number of arguments. Expecting 6")
+	}
+	if !hasRole(stub, "arbitrator") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Dispute Resolve >>>>")
+		fmt.Println("                         Caller is not an arbitrator")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute RESOLVE] Caller is not an arbitrator")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	conf, _ := stub.GetState(key + "#dispute")
+	valAsbytes, _ := stub.GetState(key)
+	if conf == nil || valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Dispute Resolve >>>>")
+		fmt.Println("                              Not exist Dispute")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute RESOLVE] Not exist Dispute")
+	}
+	dispute := Dispute{}
+	json.Unmarshal(conf, &dispute)
+	if dispute.Status != "open" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Dispute Resolve >>>>")
+		fmt.Println("                          Already resolved Dispute")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute RESOLVE] Already resolved Dispute")
+	}
+	var percent int
+	if args[3] == "release" {
+		percent = 0
+	} else if args[3] == "refund" {
+		percent = 100
+	} else if args[3] == "split" {
+		p, err := strconv.Atoi(args[4])
+		if err != nil || p < 0 || p > 100 {
+			return nil, errors.New("[Dispute RESOLVE] Invalid refund percent: " + args[4])
+		}
+		percent = p
+	} else {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Dispute Resolve >>>>")
+		fmt.Println("                          Invalid outcome: " + args[3])
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute RESOLVE] Invalid outcome: " + args[3])
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	amount, err := strconv.Atoi(tradeRec.TA)
+	if err != nil {
+		return nil, errors.New("[Dispute RESOLVE] Invalid TA: " + tradeRec.TA)
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Dispute RESOLVE] " + err.Error())
+	}
+	refund := amount * percent / 100
+
+	dispute.Status = "resolved"
+	dispute.Outcome = args[3]
+	dispute.Percent = strconv.Itoa(percent)
+	dispute.Refund = strconv.Itoa(refund)
+	dispute.Payout = strconv.Itoa(amount - refund)
+	dispute.Arbitrator = callerAttr(stub, "email")
+	dispute.Note = args[5]
+	dispute.ResolveTime = now.String()
+	jsonAsBytes, _ := marshalDoc(&dispute)
+	stub.PutState(key+"#dispute", jsonAsBytes)
+
+	tradeRec.Status = "resolved"
+	tradeRec.Refund = dispute.Refund
+	tradeRec.Payout = dispute.Payout
+	appendHistory(&tradeRec, "resolved:"+args[3]+":"+dispute.Refund+":"+dispute.Payout+"@"+now.Format("20060102"))
+	jsonAsBytes, _ = marshalDoc(&tradeRec)
+	stub.PutState(key, jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                 <<<< Dispute Resolve chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return json.Marshal(dispute)
+}
+
+func (t *PS) read_dispute(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Dispute Read >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Dispute QUERY] Incorrect number of arguments. Expecting 3")
+	}
+	valAsbytes, _ := stub.GetState(args[0] + "#" + args[1] + "#" + args[2] + "#dispute")
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Dispute Read >>>>")
+		fmt.Println("                              Not exist Dispute")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[Dispute QUERY] Not exist Dispute")
+	}
+	return valAsbytes, nil
+}
+
+// TH 에 "/" 로 구분하여 이력 추가
+func appendHistory(tradeRec *TradeRec, entry string) {
+	if tradeRec.TH == "" || tradeRec.TH == "none" {
+		tradeRec.TH = entry
+		return
+	}
+	tradeRec.TH = tradeRec.TH + "/" + entry
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestOpenDisputeCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+	csid := userID(s, "cs@example.com")
+
+	// 다른 사람이 고객 이름으로 신청
+	s.as("other@example.com")
+	_, err := s.invoke("open_dispute", psid, csid, tid, "cs@example.com", "reason")
+	expectErr(t, err, "dispute in another user's name")
+	// 펫시터가 고객 이름으로 신청
+	s.as("ps@example.com")
+	_, err = s.invoke("open_dispute", psid, csid, tid, "cs@example.com", "reason")
+	expectErr(t, err, "dispute in the other party's name")
+
+	s.as("cs@example.com")
+	s.mustInvoke(t, "open_dispute", psid, csid, tid, "cs@example.com", "reason")
+
+	s.as("ps@example.com")
+	_, err = s.invoke("add_dispute_evidence", psid, csid, tid, "cs@example.com", "hash", "photo")
+	expectErr(t, err, "evidence in the other party's name")
+	s.mustInvoke(t, "add_dispute_evidence", psid, csid, tid, "ps@example.com", "hash", "photo")
+}
+
+func TestOpenDisputeStatus(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+	csid := userID(s, "cs@example.com")
+
+	s.as("cs@example.com")
+	s.mustInvoke(t, "cancel_booking", psid, csid, tid)
+	_, err := s.invoke("open_dispute", psid, csid, tid, "cs@example.com", "reason")
+	expectErr(t, err, "dispute on a cancelled trade")
+	if existState(s, tradeKey(psid, csid, tid)+"#dispute") {
+		t.Fatal("dispute stored on a cancelled trade")
+	}
+}
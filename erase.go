@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const erased = "[erased]"
+
+type ErasureReceipt struct { // Erasure receipt (KEY: _erasure#TxID)
+	TxID          string
+	UserID        string // Now unlinkable user ID kept in anonymized trades
+	Deleted       string // Number of deleted records
+	Anonymized    string // Number of anonymized records
+	RequestTime   string
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
+}
+
+// 이메일 (본인 또는 관리자)
+// 개인정보 레코드는 삭제하고, 거래 기록은 금액/일자만 남긴 채 익명화한다.
+// 이메일 -> ID 매핑을 지우므로 남은 ID 는 더 이상 사용자와 연결되지 않는다.
+func (t *PS) erase_user(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< User Erase >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[User ERASE] Incorrect number of arguments. Expecting 1")
+	}
+	email := args[0]
+	if callerAttr(stub, "email") != email && !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< User Erase >>>>")
+		fmt.Println("                           Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[User ERASE] Not allowed caller")
+	}
+	if !strings.Contains(email, "@") {
+		return nil, errors.New("[User ERASE] Not an email: " + email)
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[User ERASE] " + err.Error())
+	}
+
+	// 이메일로 저장된 기존 레코드는 먼저 ID 로 옮긴다
+	// (삭제는 어차피 전체 상태를 한 번 훑으므로 나누지 않는다)
+	id := registerUser(stub, email)
+	if _, err := migrateKeys(stub, email, id, "", 0); err != nil {
+		return nil, errors.New("[User ERASE] " + err.Error())
+	}
+	// 펫시터로서의 예약과 고객으로서의 예약 모두
+	active := append(activeBookings(stub, id+"#t", now), activeBookings(stub, consumerIndexKey(id), now)...)
+	if len(active) > 0 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< User Erase >>>>")
+		fmt.Println("                           Exist active booking")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[User ERASE] Exist active booking: " + strings.Join(active, ","))
+	}
+
+	var deleted, anonymized int
+	for _, hid := range homeIDs(stub, id) {
+		deleteHome(stub, id, hid)
+		deleted++
+	}
+
+	iter, err := stub.RangeQueryState(firstKey, lastKey)
+	if err != nil {
+		return nil, errors.New("[User ERASE] " + err.Error())
+	}
+	var dels []string
+	puts := map[string][]byte{}
+	for iter.HasNext() {
+		key, val, err := iter.Next()
+		if err != nil {
+			iter.Close()
+			return nil, errors.New("[User ERASE] " + err.Error())
+		}
+		parts := strings.Split(key, "#")
+		if parts[0] == "_geo" && len(parts) == 4 && parts[2] == id {
+			dels = append(dels, key)
+			continue
+		}
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		if parts[0] == id && len(parts) == 2 && (parts[1] == "home" || parts[1] == "homes" || parts[1] == "policy") {
+			dels = append(dels, key)
+		} else if parts[0] == id && len(parts) == 3 && (parts[1] == "home" || parts[1] == "addr") {
+			dels = append(dels, key)
+		} else if len(parts) >= 3 && (parts[0] == id || parts[1] == id) && parts[1] != "home" && parts[1] != "addr" {
+			if newVal := anonymizeTradeRecord(id, parts, val); newVal != nil {
+				puts[key] = newVal
+			}
+		}
+	}
+	iter.Close()
+	for _, key := range dels {
+		stub.DelState(key)
+		deleted++
+	}
+	for key, val := range puts {
+		stub.PutState(key, val)
+		anonymized++
+	}
+
+	conf, _ := stub.GetState(id)
+	if conf != nil {
+		petsitter := Petsitter{}
+		json.Unmarshal(conf, &petsitter)
+		tomb := Petsitter{}
+		tomb.Nickname = erased
+		tomb.Status = "deleted"
+		tomb.StatusNote = erased
+		tomb.Rating = petsitter.Rating
+		tomb.ReviewNum = petsitter.ReviewNum
+		tomb.ReviewSum = petsitter.ReviewSum
+		tomb.Revision = petsitter.Revision
+		tomb.SaveTime = now.String()
+		jsonAsBytes, _ := marshalDoc(&tomb)
+		stub.PutState(id, jsonAsBytes)
+		anonymized++
+	}
+	var start, end int
+	for i, v := range CCstr {
+		if v == 47 {
+			end = i
+			if CCstr[start+1:end+1] == id+"/" {
+				CCstr = CCstr[:start+1] + CCstr[end+1:]
+				break
+			}
+			start = end
+		}
+	}
+	stub.PutState("_CCstr", []byte(CCstr))
+	deleteUser(stub, email)
+	deleted++
+
+	receipt := ErasureReceipt{}
+	receipt.TxID = stub.GetTxID()
+	receipt.UserID = id
+	receipt.Deleted = strconv.Itoa(deleted)
+	receipt.Anonymized = strconv.Itoa(anonymized)
+	receipt.RequestTime = now.String()
+	jsonAsBytes, _ := marshalDoc(&receipt)
+	stub.PutState("_erasure#"+receipt.TxID, jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                   <<<< User Erase chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return jsonAsBytes, nil
+}
+
+func (t *PS) read_erasure_receipt(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Erasure Receipt Read >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Erasure QUERY] Incorrect number of arguments. Expecting 1")
+	}
+	valAsbytes, _ := stub.GetState("_erasure#" + args[0])
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Erasure Receipt Read >>>>")
+		fmt.Println("                            Not exist Receipt")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[Erasure QUERY] Not exist Receipt")
+	}
+	return valAsbytes, nil
+}
+
+// 거래와 하위 레코드에서 지운 사용자의 닉네임과 작성 내용을 지운다
+// 상대방이 작성한 내용과 금액, 일자, 점수, 체인코드가 추가한 거래 이력은 유지한다.
+func anonymizeTradeRecord(id string, parts []string, val []byte) []byte {
+	if len(parts) == 3 {
+		tradeRec := TradeRec{}
+		json.Unmarshal(val, &tradeRec)
+		if tradeRec.PSID == id {
+			tradeRec.PSNickname = erased
+		}
+		if tradeRec.CSID == id {
+			tradeRec.TH = eraseHistoryText(tradeRec.TH)
+		}
+		ret, _ := marshalDoc(&tradeRec)
+		return ret
+	}
+	if len(parts) != 4 {
+		return nil
+	}
+	switch parts[3] {
+	case "review":
+		review := Review{}
+		json.Unmarshal(val, &review)
+		if review.CSID != id {
+			return nil
+		}
+		review.Text = erased
+		ret, _ := marshalDoc(&review)
+		return ret
+	case "creview":
+		review := ConsumerReview{}
+		json.Unmarshal(val, &review)
+		if review.PSID != id {
+			return nil
+		}
+		review.Text = erased
+		ret, _ := marshalDoc(&review)
+		return ret
+	case "dispute":
+		dispute := Dispute{}
+		json.Unmarshal(val, &dispute)
+		if dispute.Opener == id {
+			dispute.Reason = erased
+		}
+		for i := range dispute.Evidence {
+			if dispute.Evidence[i].Submitter == id {
+				dispute.Evidence[i].Description = erased
+			}
+		}
+		ret, _ := marshalDoc(&dispute)
+		return ret
+	}
+	return nil
+}
+
+// appendHistory 가 추가한 이력 (accepted@, cancelled:, disputed:, resolved:)
+var historyPrefixes = []string{"accepted@", "cancelled:", "disputed:", "resolved:"}
+
+// TH 에서 고객이 save_tran 으로 입력한 내용만 지운다
+func eraseHistoryText(th string) string {
+	if th == "" || th == "none" {
+		return th
+	}
+	var ret []string
+	for _, v := range strings.Split(th, "/") {
+		system := false
+		for _, prefix := range historyPrefixes {
+			if strings.HasPrefix(v, prefix) && strings.Contains(v, "@") {
+				system = true
+			}
+		}
+		if system {
+			ret = append(ret, v)
+		} else if len(ret) == 0 || ret[len(ret)-1] != erased {
+			ret = append(ret, erased)
+		}
+	}
+	return strings.Join(ret, "/")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEraseUserCaller(t *testing.T) {
+	s := newTestStub(t)
+	s.petsitter(t, "ps@example.com")
+	s.as("other@example.com")
+	_, err := s.invoke("erase_user", "ps@example.com")
+	expectErr(t, err, "erase by another user")
+}
+
+func TestEraseConsumerKeepsOtherParty(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.acceptedTrade(t, "ps@example.com", "cs@example.com", "20240520", "20240525")
+	csid := userID(s, "cs@example.com")
+	key := tradeKey(psid, csid, tid)
+
+	s.as("cs@example.com")
+	s.mustInvoke(t, "submit_review", psid, csid, tid, "5", "consumer text")
+	s.as("ps@example.com")
+	s.mustInvoke(t, "submit_consumer_review", psid, csid, tid, "4", "4", "petsitter text")
+	s.mustInvoke(t, "open_dispute", psid, csid, tid, "ps@example.com", "petsitter reason")
+	s.mustInvoke(t, "add_dispute_evidence", psid, csid, tid, "ps@example.com", "h1", "petsitter evidence")
+	s.as("cs@example.com")
+	s.mustInvoke(t, "add_dispute_evidence", psid, csid, tid, "cs@example.com", "h2", "consumer evidence")
+
+	// 분쟁 중에는 고객도 삭제할 수 없다
+	_, err := s.invoke("erase_user", "cs@example.com")
+	expectErr(t, err, "erase during a dispute")
+	s.attrs = map[string]string{"email": "arb@example.com", "role": "arbitrator"}
+	s.mustInvoke(t, "resolve_dispute", psid, csid, tid, "split", "50", "note")
+
+	s.as("cs@example.com")
+	ret := s.mustInvoke(t, "erase_user", "cs@example.com")
+	if strings.Contains(string(ret), "EmailHash") {
+		t.Fatalf("receipt = %s", ret)
+	}
+	if userID(s, "cs@example.com") != "cs@example.com" {
+		t.Fatal("email mapping not removed")
+	}
+
+	tradeRec := TradeRec{}
+	review := Review{}
+	creview := ConsumerReview{}
+	dispute := Dispute{}
+	for k, v := range map[string]interface{}{key: &tradeRec, key + "#review": &review, key + "#creview": &creview, key + "#dispute": &dispute} {
+		conf, _ := s.GetState(k)
+		json.Unmarshal(conf, v)
+	}
+	if tradeRec.PSNickname != "nick" || !strings.HasPrefix(tradeRec.TH, erased+"/accepted@") || !strings.Contains(tradeRec.TH, "/resolved:split:50000:50000@") || tradeRec.TA != "100000" {
+		t.Fatalf("trade = %+v", tradeRec)
+	}
+	if review.Text != erased || creview.Text != "petsitter text" {
+		t.Fatalf("reviews = %q, %q", review.Text, creview.Text)
+	}
+	if dispute.Reason != "petsitter reason" || dispute.Evidence[0].Description != "petsitter evidence" || dispute.Evidence[1].Description != erased {
+		t.Fatalf("dispute = %+v", dispute)
+	}
+}
+
+func TestEraseConsumerWithUpcomingBooking(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+
+	s.as("cs@example.com")
+	_, err := s.invoke("erase_user", "cs@example.com")
+	expectErr(t, err, "erase with an upcoming booking")
+
+	s.mustInvoke(t, "cancel_booking", psid, userID(s, "cs@example.com"), tid)
+	s.mustInvoke(t, "erase_user", "cs@example.com")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+type ExportPage struct { // export_records result
+	Kind   string
+	Format string // csv (RFC 4180) or jsonl
+	Count  string
+	Next   string // Start key of the next page ("" if last)
+	Data   string
+}
+
+type exporter struct {
+	docType string
+	header  []string
+	row     func(stub shim.ChaincodeStubInterface, key string, val []byte) ([]string, interface{})
+}
+
+var exporters = map[string]exporter{
+	"petsitters": {docPetsitter, []string{"ID", "Nickname", "CostL", "CostM", "CostS", "Start", "End", "Except", "TotalNum", "NumL", "NumM", "NumS", "Home", "HomeInfo", "SaveTime", "Status", "StatusNote", "Rating", "ReviewNum", "ReviewSum"}, exportPetsitter},
+	"homes":      {docHome, []string{"PSID", "ID", "Name", "State", "City", "Type", "Room", "Elevator", "Parking", "Lat", "Lng", "SaveTime"}, exportHome},
+	"trades":     {docTrade, []string{"TradeID", "PSID", "PSNickname", "CSID", "TS", "TE", "TC", "TA", "TH", "Status", "Refund", "Payout", "HomeID"}, exportTrade},
+}
+
+// 종류(petsitters, homes, trades), 형식(csv, jsonl), 시작 키, 최대 건수 (관리자)
+// CSV 머리글은 첫 페이지(시작 키 "")에만 넣는다.
+func (t *PS) export_records(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 4 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                              <<<< Export >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 4")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[EXPORT] Incorrect number of arguments. Expecting 4")
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                              <<<< Export >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[EXPORT] Caller is not an admin")
+	}
+	exp, ok := exporters[args[0]]
+	if !ok {
+		return nil, errors.New("[EXPORT] Unknown kind: " + args[0])
+	}
+	if args[1] != "csv" && args[1] != "jsonl" {
+		return nil, errors.New("[EXPORT] Unknown format: " + args[1])
+	}
+	limit, err := strconv.Atoi(args[3])
+	if err != nil || limit <= 0 {
+		return nil, errors.New("[EXPORT] Invalid limit: " + args[3])
+	}
+	start := args[2]
+	if start == "" {
+		start = firstKey
+	}
+	iter, err := stub.RangeQueryState(start, lastKey)
+	if err != nil {
+		return nil, errors.New("[EXPORT] " + err.Error())
+	}
+	defer iter.Close()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true
+	if args[1] == "csv" && args[2] == "" {
+		w.Write(exp.header)
+	}
+	page := ExportPage{Kind: args[0], Format: args[1]}
+	count := 0
+	for iter.HasNext() {
+		key, val, err := iter.Next()
+		if err != nil {
+			return nil, errors.New("[EXPORT] " + err.Error())
+		}
+		if docTypeOf(key) != exp.docType {
+			continue
+		}
+		if count == limit {
+			page.Next = key
+			break
+		}
+		count++
+		row, obj := exp.row(stub, key, val)
+		if args[1] == "csv" {
+			w.Write(row)
+			continue
+		}
+		line, _ := json.Marshal(obj)
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	w.Flush()
+	page.Count = strconv.Itoa(count)
+	page.Data = buf.String()
+	fmt.Println()
+	fmt.Println("=======================================================================")
+	fmt.Println("                              <<<< Export >>>>")
+	fmt.Println("                             Export success")
+	fmt.Println("=======================================================================")
+	fmt.Println()
+	return json.Marshal(page)
+}
+
+func exportPetsitter(stub shim.ChaincodeStubInterface, key string, val []byte) ([]string, interface{}) {
+	p := Petsitter{}
+	json.Unmarshal(val, &p)
+	encKey, _ := piiKey(stub)
+	decryptFields(encKey, key, petsitterPII(&p))
+	row := []string{key, p.Nickname, p.CostL, p.CostM, p.CostS, p.Start, p.End, p.Except, p.TotalNum, p.NumL, p.NumM, p.NumS, p.Home, p.HomeInfo, p.SaveTime, p.Status, p.StatusNote, p.Rating, p.ReviewNum, p.ReviewSum}
+	return row, struct {
+		ID string
+		Petsitter
+	}{key, p}
+}
+
+// 상세 주소는 내보내지 않는다
+func exportHome(stub shim.ChaincodeStubInterface, key string, val []byte) ([]string, interface{}) {
+	h := HomeAsset{}
+	json.Unmarshal(val, &h)
+	h = publicHome(h)
+	if h.ID == "" {
+		h.ID = defaultHome
+	}
+	psid := strings.Split(key, "#")[0]
+	row := []string{psid, h.ID, h.Name, h.State, h.City, h.Type, h.Room, h.Elevator, h.Parking, h.Lat, h.Lng, h.SaveTime}
+	return row, struct {
+		PSID string
+		HomeAsset
+	}{psid, h}
+}
+
+func exportTrade(stub shim.ChaincodeStubInterface, key string, val []byte) ([]string, interface{}) {
+	r := TradeRec{}
+	json.Unmarshal(val, &r)
+	encKey, _ := piiKey(stub)
+	decryptFields(encKey, key, tradePII(&r))
+	if r.TradeID == "" {
+		r.TradeID = strings.Split(key, "#")[2]
+	}
+	row := []string{r.TradeID, r.PSID, r.PSNickname, r.CSID, r.TS, r.TE, r.TC, r.TA, r.TH, r.Status, r.Refund, r.Payout, r.HomeID}
+	return row, r
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func exportPage(t *testing.T, s *testStub, kind, format, start, limit string) ExportPage {
+	t.Helper()
+	s.admin()
+	ret, err := s.query("export_records", kind, format, start, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := ExportPage{}
+	json.Unmarshal(ret, &page)
+	return page
+}
+
+func TestExportAdminOnly(t *testing.T) {
+	s := newTestStub(t)
+	s.petsitter(t, "ps@example.com")
+	_, err := s.query("export_records", "petsitters", "csv", "", "10")
+	expectErr(t, err, "export by a non-admin")
+}
+
+func TestExportCSVQuoting(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	nick := "a, \"b\"\nc"
+	patch, _ := json.Marshal(map[string]string{"Nickname": nick})
+	s.mustInvoke(t, "patch_petsitter", "ps@example.com", string(patch))
+
+	page := exportPage(t, s, "petsitters", "csv", "", "10")
+	if !strings.Contains(page.Data, `"a, ""b""`+"\r\nc\"") || !strings.HasSuffix(page.Data, "\r\n") {
+		t.Fatalf("data = %q", page.Data)
+	}
+	rows, err := csv.NewReader(strings.NewReader(page.Data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0][0] != "ID" || rows[1][0] != psid || rows[1][1] != nick {
+		t.Fatalf("rows = %q", rows)
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+
+	page := exportPage(t, s, "trades", "jsonl", "", "10")
+	lines := strings.Split(strings.TrimSuffix(page.Data, "\n"), "\n")
+	if page.Count != "1" || len(lines) != 1 {
+		t.Fatalf("page = %+v", page)
+	}
+	tradeRec := TradeRec{}
+	if err := json.Unmarshal([]byte(lines[0]), &tradeRec); err != nil {
+		t.Fatal(err)
+	}
+	if tradeRec.TradeID != tid || tradeRec.PSID != psid || tradeRec.TA != "100000" {
+		t.Fatalf("trade = %+v", tradeRec)
+	}
+}
+
+func TestExportPaging(t *testing.T) {
+	s := newTestStub(t)
+	want := map[string]bool{}
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		want[s.petsitter(t, email)] = true
+	}
+
+	seen := map[string]bool{}
+	start := ""
+	for i := 0; ; i++ {
+		if i > 5 {
+			t.Fatal("export does not finish")
+		}
+		page := exportPage(t, s, "petsitters", "csv", start, "2")
+		rows, err := csv.NewReader(strings.NewReader(page.Data)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 머리글은 첫 페이지에만
+		if start == "" {
+			rows = rows[1:]
+		}
+		for _, row := range rows {
+			if row[0] == "ID" || seen[row[0]] {
+				t.Fatalf("page %d: unexpected row %q", i, row)
+			}
+			seen[row[0]] = true
+		}
+		if page.Next == "" {
+			break
+		}
+		start = page.Next
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("exported %v, want %v", seen, want)
+	}
+	for id := range want {
+		if !seen[id] {
+			t.Fatalf("missing %s", id)
+		}
+	}
+}
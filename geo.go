@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	geoBase32    = "0123456789bcdefghjkmnpqrstuvwxyz"
+	geoPrecision = 6 // 약 1.2km x 0.6km
+	earthRadius  = 6371.0
+)
+
+type NearbyRet struct { // search_nearby result
+	PSID     string
+	HomeID   string
+	Name     string
+	State    string
+	City     string
+	Lat      string
+	Lng      string
+	Distance string // km
+}
+
+type byDistance struct {
+	ret  []NearbyRet
+	dist []float64
+}
+
+func (a byDistance) Len() int { return len(a.ret) }
+func (a byDistance) Swap(i, j int) {
+	a.ret[i], a.ret[j] = a.ret[j], a.ret[i]
+	a.dist[i], a.dist[j] = a.dist[j], a.dist[i]
+}
+func (a byDistance) Less(i, j int) bool { return a.dist[i] < a.dist[j] }
+
+// 이메일, 집 ID, 위도, 경도 (none 이면 삭제, 본인 또는 관리자)
+func (t *PS) set_home_location(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 4 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Home Location >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 4")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home LOCATION] Incorrect number of arguments. Expecting 4")
+	}
+	if !selfOrAdmin(stub, args[0]) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Home Location >>>>")
+		fmt.Println("                           Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home LOCATION] Not allowed caller")
+	}
+	key := homeKey(args[0], args[1])
+	conf, _ := stub.GetState(key)
+	if conf == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Home Location >>>>")
+		fmt.Println("                               Not exist Home")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home LOCATION] Not exist Home")
+	}
+	homeAsset := HomeAsset{}
+	json.Unmarshal(conf, &homeAsset)
+	removeGeoIndex(stub, args[0], args[1], homeAsset)
+	if args[2] == "none" || args[3] == "none" {
+		homeAsset.Lat = ""
+		homeAsset.Lng = ""
+	} else {
+		lat, err1 := strconv.ParseFloat(args[2], 64)
+		lng, err2 := strconv.ParseFloat(args[3], 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			fmt.Println()
+			fmt.Println("=======================================================================")
+			fmt.Println("                         <<<< Home Location >>>>")
+			fmt.Println("                         Invalid latitude/longitude")
+			fmt.Println("=======================================================================")
+			fmt.Println()
+			return nil, errors.New("[Home LOCATION] Invalid latitude/longitude")
+		}
+		homeAsset.Lat = args[2]
+		homeAsset.Lng = args[3]
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Home LOCATION] " + err.Error())
+	}
+	homeAsset.SaveTime = now.String()
+	jsonAsBytes, _ := marshalDoc(&homeAsset)
+	stub.PutState(key, jsonAsBytes)
+	addGeoIndex(stub, args[0], args[1], homeAsset)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                 <<<< Home Location chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// 위도, 경도, 반경(km)
+func (t *PS) search_nearby(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< SearchNearby >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[SearchNearby] Incorrect number of arguments. Expecting 3")
+	}
+	lat, err1 := strconv.ParseFloat(args[0], 64)
+	lng, err2 := strconv.ParseFloat(args[1], 64)
+	radius, err3 := strconv.ParseFloat(args[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 || radius <= 0 {
+		return nil, errors.New("[SearchNearby] Invalid latitude/longitude/radius")
+	}
+
+	result := byDistance{[]NearbyRet{}, []float64{}}
+	seen := map[string]bool{}
+	for _, cell := range geoCells(lat, lng, radius) {
+		iter, err := stub.RangeQueryState("_geo#"+cell, "_geo#"+cell+"~")
+		if err != nil {
+			return nil, errors.New("[SearchNearby] " + err.Error())
+		}
+		for iter.HasNext() {
+			key, _, err := iter.Next()
+			if err != nil {
+				iter.Close()
+				return nil, errors.New("[SearchNearby] " + err.Error())
+			}
+			// _geo#geohash#email#hid
+			parts := strings.Split(key, "#")
+			if len(parts) != 4 || seen[parts[2]+"#"+parts[3]] {
+				continue
+			}
+			seen[parts[2]+"#"+parts[3]] = true
+			ps, _ := stub.GetState(parts[2])
+			psh, _ := stub.GetState(homeKey(parts[2], parts[3]))
+			if ps == nil || psh == nil {
+				continue
+			}
+			srt := Petsitter{}
+			srth := HomeAsset{}
+			json.Unmarshal(ps, &srt)
+			json.Unmarshal(psh, &srth)
+			if !petsitterActive(srt) {
+				continue
+			}
+			hlat, err1 := strconv.ParseFloat(srth.Lat, 64)
+			hlng, err2 := strconv.ParseFloat(srth.Lng, 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			dist := haversine(lat, lng, hlat, hlng)
+			if dist > radius {
+				continue
+			}
+			result.ret = append(result.ret, NearbyRet{parts[2], parts[3], srth.Name, srth.State, srth.City, srth.Lat, srth.Lng, strconv.FormatFloat(dist, 'f', 2, 64)})
+			result.dist = append(result.dist, dist)
+		}
+		iter.Close()
+	}
+	sort.Sort(result)
+	return json.Marshal(result.ret)
+}
+
+// 위치 인덱스 (KEY: _geo#geohash#User ID#집 ID)
+func geoKey(email, hid string, homeAsset HomeAsset) string {
+	lat, err1 := strconv.ParseFloat(homeAsset.Lat, 64)
+	lng, err2 := strconv.ParseFloat(homeAsset.Lng, 64)
+	if err1 != nil || err2 != nil {
+		return ""
+	}
+	if hid == "" {
+		hid = defaultHome
+	}
+	return "_geo#" + geohash(lat, lng, geoPrecision) + "#" + email + "#" + hid
+}
+
+func addGeoIndex(stub shim.ChaincodeStubInterface, email, hid string, homeAsset HomeAsset) {
+	if key := geoKey(email, hid, homeAsset); key != "" {
+		stub.PutState(key, []byte(homeKey(email, hid)))
+	}
+}
+
+func removeGeoIndex(stub shim.ChaincodeStubInterface, email, hid string, homeAsset HomeAsset) {
+	if key := geoKey(email, hid, homeAsset); key != "" {
+		stub.DelState(key)
+	}
+}
+
+func geohash(lat, lng float64, precision int) string {
+	latR := [2]float64{-90, 90}
+	lngR := [2]float64{-180, 180}
+	var ret []byte
+	even := true
+	bit, ch := 0, 0
+	for len(ret) < precision {
+		if even {
+			mid := (lngR[0] + lngR[1]) / 2
+			if lng >= mid {
+				ch = ch<<1 | 1
+				lngR[0] = mid
+			} else {
+				ch = ch << 1
+				lngR[1] = mid
+			}
+		} else {
+			mid := (latR[0] + latR[1]) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				latR[0] = mid
+			} else {
+				ch = ch << 1
+				latR[1] = mid
+			}
+		}
+		even = !even
+		bit++
+		if bit == 5 {
+			ret = append(ret, geoBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(ret)
+}
+
+// 셀 크기(도)
+func geoCellSize(precision int) (float64, float64) {
+	bits := uint(precision * 5)
+	latBits := bits / 2
+	lngBits := bits - latBits
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+// 반경을 덮는 중심 셀과 주변 8개 셀
+func geoCells(lat, lng, radius float64) []string {
+	precision := geoPrecision
+	for ; precision > 1; precision-- {
+		h, w := geoCellSize(precision)
+		hkm := h * 111.32
+		wkm := w * 111.32 * math.Cos(lat*math.Pi/180)
+		if hkm >= radius && wkm >= radius {
+			break
+		}
+	}
+	h, w := geoCellSize(precision)
+	var cells []string
+	seen := map[string]bool{}
+	for _, dy := range []float64{-h, 0, h} {
+		for _, dx := range []float64{-w, 0, w} {
+			y := math.Max(-90, math.Min(90, lat+dy))
+			x := lng + dx
+			if x < -180 {
+				x += 360
+			} else if x >= 180 {
+				x -= 360
+			}
+			cell := geohash(y, x, precision)
+			if !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+		}
+	}
+	return cells
+}
+
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	dlat := (lat2 - lat1) * math.Pi / 180
+	dlng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dlng/2)*math.Sin(dlng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
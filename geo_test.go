@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func nearby(t *testing.T, s *testStub, lat, lng, radius string) []NearbyRet {
+	t.Helper()
+	ret, err := s.query("search_nearby", lat, lng, radius)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var homes []NearbyRet
+	json.Unmarshal(ret, &homes)
+	return homes
+}
+
+func TestSetHomeLocationCaller(t *testing.T) {
+	s := newTestStub(t)
+	s.petsitter(t, "ps@example.com")
+	s.mustInvoke(t, "save_named_home", namedHomeArgs("ps@example.com", "cottage", "cottage")...)
+	s.mustInvoke(t, "set_home_location", "ps@example.com", "cottage", "37.5", "127.03")
+
+	s.as("other@example.com")
+	_, err := s.invoke("set_home_location", "ps@example.com", "cottage", "none", "none")
+	expectErr(t, err, "clear location by another user")
+	_, err = s.invoke("set_home_location", "ps@example.com", "cottage", "35.1", "129.0")
+	expectErr(t, err, "move location by another user")
+	if homes := nearby(t, s, "37.5", "127.03", "1"); len(homes) != 1 {
+		t.Fatalf("nearby = %+v", homes)
+	}
+
+	s.admin()
+	s.mustInvoke(t, "set_home_location", "ps@example.com", "cottage", "none", "none")
+	if homes := nearby(t, s, "37.5", "127.03", "1"); len(homes) != 0 {
+		t.Fatalf("nearby = %+v", homes)
+	}
+}
+
+func TestSearchNearbyOrder(t *testing.T) {
+	s := newTestStub(t)
+	s.petsitter(t, "a@example.com")
+	s.mustInvoke(t, "save_named_home", namedHomeArgs("a@example.com", "far", "far")...)
+	s.mustInvoke(t, "set_home_location", "a@example.com", "far", "37.52", "127.03")
+	s.petsitter(t, "b@example.com")
+	s.mustInvoke(t, "save_named_home", namedHomeArgs("b@example.com", "near", "near")...)
+	s.mustInvoke(t, "set_home_location", "b@example.com", "near", "37.501", "127.03")
+
+	homes := nearby(t, s, "37.5", "127.03", "5")
+	if len(homes) != 2 || homes[0].HomeID != "near" || homes[1].HomeID != "far" {
+		t.Fatalf("nearby = %+v", homes)
+	}
+	if homes := nearby(t, s, "37.5", "127.03", "1"); len(homes) != 1 {
+		t.Fatalf("nearby within 1km = %+v", homes)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 기본 집 (기존 save_home* 함수가 사용하는 User ID#home)
+const defaultHome = "default"
+
+// 이메일, 집 ID, 이름, 시/도, 시/군/구, 도로명, 상세주소, 우편번호, 유형, 방, 엘리베이터, 주차
+func (t *PS) save_named_home(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 12 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 12")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] Incorrect number of arguments. Expecting 12")
+	}
+	if !selfOrAdmin(stub, args[0]) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                              Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] Not allowed caller")
+	}
+	confUser, _ := stub.GetState(args[0])
+	if confUser == nil || petsitterDeleted(confUser) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                               Not exist Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] Not exist Petsitter")
+	}
+	if args[1] == "" || strings.ContainsAny(args[1], "#/") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                           Invalid home ID: " + args[1])
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] Invalid home ID: " + args[1])
+	}
+	conf, _ := stub.GetState(homeKey(args[0], args[1]))
+	if conf != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                              Already exist Home")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] Already exist Home")
+	}
+	homeAsset := HomeAsset{}
+	homeAsset.ID = args[1]
+	homeAsset.Name = args[2]
+	homeAsset.State = args[3]
+	homeAsset.City = args[4]
+	homeAsset.Street = args[5]
+	homeAsset.Adt = args[6]
+	homeAsset.Code = args[7]
+	homeAsset.Type = args[8]
+	homeAsset.Room = args[9]
+	homeAsset.Elevator = args[10]
+	homeAsset.Parking = args[11]
+	if err := normalizeAddress(stub, &homeAsset); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                              Invalid address")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] " + err.Error())
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Home INSSERT] " + err.Error())
+	}
+	homeAsset.SaveTime = now.String()
+	if err := putHomeAddress(stub, args[0], args[1], &homeAsset, nil); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Home Insert >>>>")
+		fmt.Println("                           Address encryption error")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home INSSERT] " + err.Error())
+	}
+	jsonAsBytes, _ := marshalDoc(&homeAsset)
+	stub.PutState(homeKey(args[0], args[1]), jsonAsBytes)
+	addHomeIndex(stub, args[0], args[1])
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Home Insert chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// 이메일, 집 ID, 이름, ... (변경하지 않는 값은 none) [, 기대 revision]
+func (t *PS) modify_named_home(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 12 && len(args) != 13 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Change >>>>")
+		fmt.Println("            Incorrect number of arguments. Expecting 12 or 13")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home CHANGE] Incorrect number of arguments. Expecting 12 or 13")
+	}
+	if !selfOrAdmin(stub, args[0]) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Change >>>>")
+		fmt.Println("                              Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home CHANGE] Not allowed caller")
+	}
+	if !existState(stub, homeKey(args[0], args[1])) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Change >>>>")
+		fmt.Println("                               Not exist Home")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home CHANGE] Not exist Home")
+	}
+	p := argsPatch([]string{"Name", "State", "City", "Street", "Adt", "Code", "Type", "Room", "Elevator", "Parking"}, args[2:], true)
+	if _, err := patchHome(stub, args[0], args[1], p, optArg(args, 12)); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Change >>>>")
+		fmt.Println("                               Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home CHANGE] " + err.Error())
+	}
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Home Modify chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// 이메일, 집 ID
+func (t *PS) delete_named_home(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 2 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                        <<<< Home Delete >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 2")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home DELETE] Incorrect number of arguments. Expecting 2")
+	}
+	if !selfOrAdmin(stub, args[0]) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Delete >>>>")
+		fmt.Println("                              Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home DELETE] Not allowed caller")
+	}
+	key := homeKey(args[0], args[1])
+	conf, _ := stub.GetState(key)
+	if conf == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Delete >>>>")
+		fmt.Println("                              Not exist Home")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home DELETE] Not exist Home")
+	}
+	deleteHome(stub, args[0], args[1])
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Home Delete chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// 이메일, 집 ID
+func (t *PS) read_named_home(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 2 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Read >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 2")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home QUERY] Incorrect number of arguments. Expecting 2")
+	}
+	valAsbytes, _ := stub.GetState(homeKey(args[0], args[1]))
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home Read >>>>")
+		fmt.Println("                              Not exist Home")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[Home QUERY] Not exist Home")
+	}
+	homeAsset := HomeAsset{}
+	json.Unmarshal(valAsbytes, &homeAsset)
+	return json.Marshal(publicHome(homeAsset))
+}
+
+func (t *PS) list_homes(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Home List >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home QUERY] Incorrect number of arguments. Expecting 1")
+	}
+	homes := []HomeAsset{}
+	for _, hid := range homeIDs(stub, args[0]) {
+		valAsbytes, _ := stub.GetState(homeKey(args[0], hid))
+		if valAsbytes == nil {
+			continue
+		}
+		homeAsset := HomeAsset{}
+		json.Unmarshal(valAsbytes, &homeAsset)
+		if homeAsset.ID == "" {
+			homeAsset.ID = hid
+		}
+		homes = append(homes, publicHome(homeAsset))
+	}
+	return json.Marshal(homes)
+}
+
+// User ID#home (기본) 또는 User ID#home#ID
+func homeKey(email, hid string) string {
+	if hid == "" || hid == defaultHome {
+		return email + "#home"
+	}
+	return email + "#home#" + hid
+}
+
+// 집 ID 목록 (KEY: User ID#homes)
+func homeIDs(stub shim.ChaincodeStubInterface, email string) []string {
+	var hstr string
+	confh, _ := stub.GetState(email + "#homes")
+	json.Unmarshal(confh, &hstr)
+	ids := splitIndex(hstr)
+	if len(ids) == 0 && existState(stub, email+"#home") {
+		ids = []string{defaultHome}
+	}
+	return ids
+}
+
+func addHomeIndex(stub shim.ChaincodeStubInterface, email, hid string) {
+	ids := homeIDs(stub, email)
+	for _, v := range ids {
+		if v == hid {
+			return
+		}
+	}
+	putHomeIndex(stub, email, append(ids, hid))
+}
+
+func removeHomeIndex(stub shim.ChaincodeStubInterface, email, hid string) {
+	var ids []string
+	for _, v := range homeIDs(stub, email) {
+		if v != hid {
+			ids = append(ids, v)
+		}
+	}
+	putHomeIndex(stub, email, ids)
+}
+
+func putHomeIndex(stub shim.ChaincodeStubInterface, email string, ids []string) {
+	if len(ids) == 0 {
+		stub.DelState(email + "#homes")
+		return
+	}
+	hbyte, _ := json.Marshal("/" + strings.Join(ids, "/") + "/")
+	stub.PutState(email+"#homes", hbyte)
+}
+
+// 집, 위치 인덱스, 집 ID 목록에서 삭제
+func deleteHome(stub shim.ChaincodeStubInterface, email, hid string) {
+	key := homeKey(email, hid)
+	conf, _ := stub.GetState(key)
+	if conf != nil {
+		homeAsset := HomeAsset{}
+		json.Unmarshal(conf, &homeAsset)
+		removeGeoIndex(stub, email, hid, homeAsset)
+		stub.DelState(key)
+	}
+	stub.DelState(addrKey(email, hid))
+	removeHomeIndex(stub, email, hid)
+}
+
+// 지역이 일치하는 첫 번째 집
+func regionHome(stub shim.ChaincodeStubInterface, email, state string, regions []Region) (HomeAsset, bool) {
+	for _, hid := range homeIDs(stub, email) {
+		valAsbytes, _ := stub.GetState(homeKey(email, hid))
+		if valAsbytes == nil {
+			continue
+		}
+		homeAsset := HomeAsset{}
+		json.Unmarshal(valAsbytes, &homeAsset)
+		if canonicalState(regions, homeAsset.State) == state {
+			return publicHome(homeAsset), true
+		}
+	}
+	return HomeAsset{}, false
+}
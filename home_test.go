@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+func namedHomeArgs(email, hid, name string) []string {
+	return []string{email, hid, name, "서울", "강남", "테헤란로 1", "101호", "06234", "apt", "2", "Y", "Y"}
+}
+
+func TestNamedHomeCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.mustInvoke(t, "save_named_home", namedHomeArgs("ps@example.com", "cottage", "cottage")...)
+
+	s.as("other@example.com")
+	_, err := s.invoke("save_named_home", namedHomeArgs("ps@example.com", "villa", "villa")...)
+	expectErr(t, err, "save_named_home by another user")
+	_, err = s.invoke("modify_named_home", append(namedHomeArgs("ps@example.com", "cottage", "hacked")[:3], "none", "none", "none", "none", "none", "none", "none", "none", "none")...)
+	expectErr(t, err, "modify_named_home by another user")
+	_, err = s.invoke("delete_named_home", "ps@example.com", "cottage")
+	expectErr(t, err, "delete_named_home by another user")
+	if existState(s, homeKey(psid, "villa")) || readHome(s, psid, "cottage").Name != "cottage" || !existState(s, addrKey(psid, "cottage")) {
+		t.Fatal("home changed by another user")
+	}
+
+	s.as("ps@example.com")
+	s.mustInvoke(t, "delete_named_home", "ps@example.com", "cottage")
+	if existState(s, homeKey(psid, "cottage")) || len(homeIDs(s, psid)) != 0 {
+		t.Fatal("home not deleted")
+	}
+}
+
+func TestNamedHomeBookingAndSearch(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.mustInvoke(t, "save_named_home", namedHomeArgs("ps@example.com", "cottage", "cottage")...)
+
+	// 없는 집은 예약할 수 없다
+	s.now = s.now.AddDate(0, 0, -30)
+	s.as("cs@example.com")
+	_, err := s.invoke("save_tran", psid, "nick", "cs@example.com", "20240620", "20240622", "20240622", "100000", "booked", "villa")
+	expectErr(t, err, "booking of a missing home")
+	s.mustInvoke(t, "save_tran", psid, "nick", "cs@example.com", "20240620", "20240622", "20240622", "100000", "booked", "cottage")
+
+	ret, err := s.query("search_bytotal", "서울", "1", "0", "0", "0", "20240620", "20240622")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret) == 0 || string(ret[:len(psid)]) != psid {
+		t.Fatalf("search = %s", ret)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 전체 상태 조회 범위
+const (
+	firstKey = ""
+	lastKey  = "\xff"
+)
+
+type Orphan struct { // search_orphans result
+	Key    string
+	Reason string
+}
+
+func (t *PS) search_orphans(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 0 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Orphan Search >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 0")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Orphan SEARCH] Incorrect number of arguments. Expecting 0")
+	}
+	iter, err := stub.RangeQueryState(firstKey, lastKey)
+	if err != nil {
+		return nil, errors.New("[Orphan SEARCH] " + err.Error())
+	}
+	defer iter.Close()
+
+	orphans := []Orphan{}
+	for iter.HasNext() {
+		key, val, err := iter.Next()
+		if err != nil {
+			return nil, errors.New("[Orphan SEARCH] " + err.Error())
+		}
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		parts := strings.Split(key, "#")
+		if len(parts) == 2 && (parts[1] == "home" || parts[1] == "homes" || parts[1] == "t" || parts[1] == "r" || parts[1] == "policy") {
+			if !existState(stub, parts[0]) {
+				orphans = append(orphans, Orphan{key, "Not exist Petsitter " + parts[0]})
+			}
+		} else if len(parts) == 3 {
+			if !existState(stub, parts[0]) {
+				orphans = append(orphans, Orphan{key, "Not exist Petsitter " + parts[0]})
+			}
+		} else if len(parts) == 4 {
+			trade := strings.Join(parts[:3], "#")
+			if !existState(stub, trade) {
+				orphans = append(orphans, Orphan{key, "Not exist transaction " + trade})
+			}
+		}
+		if len(parts) == 2 && (parts[1] == "t" || parts[1] == "r" || parts[1] == "c") {
+			var idx string
+			json.Unmarshal(val, &idx)
+			for _, v := range splitIndex(idx) {
+				if !existState(stub, v) {
+					orphans = append(orphans, Orphan{key, "Dangling index entry " + v})
+				}
+			}
+		}
+	}
+	fmt.Println()
+	fmt.Println("=======================================================================")
+	fmt.Println("                           <<<< Orphan Search >>>>")
+	fmt.Println("                           Orphan search success")
+	fmt.Println("=======================================================================")
+	fmt.Println()
+	return json.Marshal(orphans)
+}
+
+// 거래 인덱스(PSID#t 또는 CSID#c)의 진행 중인 예약 (취소/분쟁 해결되지 않고 TE 가 지나지 않은 거래)
+func activeBookings(stub shim.ChaincodeStubInterface, idx string, now time.Time) []string {
+	var tstr string
+	var ret []string
+	conft, _ := stub.GetState(idx)
+	json.Unmarshal(conft, &tstr)
+	for _, key := range splitIndex(tstr) {
+		valAsbytes, _ := stub.GetState(key)
+		if valAsbytes == nil {
+			continue
+		}
+		tradeRec := TradeRec{}
+		json.Unmarshal(valAsbytes, &tradeRec)
+		if tradeRec.Status == "cancelled" || tradeRec.Status == "resolved" {
+			continue
+		}
+		if tradeRec.Status == "disputed" || !tradeEnded(tradeRec, now) {
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
+func existState(stub shim.ChaincodeStubInterface, key string) bool {
+	valAsbytes, _ := stub.GetState(key)
+	return valAsbytes != nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDeleteHouseCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.mustInvoke(t, "patch_home", "ps@example.com", defaultHome, `{"State":"서울","City":"강남","Code":"06234"}`)
+
+	s.as("other@example.com")
+	_, err := s.invoke("delete_house", psid)
+	expectErr(t, err, "delete_house by another user")
+	if !existState(s, homeKey(psid, defaultHome)) {
+		t.Fatal("home deleted by another user")
+	}
+
+	s.as("ps@example.com")
+	s.mustInvoke(t, "delete_house", "ps@example.com")
+	if existState(s, homeKey(psid, defaultHome)) || existState(s, addrKey(psid, defaultHome)) {
+		t.Fatal("home not deleted")
+	}
+}
+
+func TestHomeNeedsPetsitter(t *testing.T) {
+	s := newTestStub(t)
+	s.as("nobody@example.com")
+	_, err := s.invoke("patch_home", "nobody@example.com", "cottage", `{"State":"서울","City":"강남","Code":"06234"}`)
+	expectErr(t, err, "home without a petsitter")
+}
+
+func TestDeletePetsitterCascade(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.mustInvoke(t, "patch_home", "ps@example.com", "cottage", `{"State":"서울","City":"강남","Code":"06234"}`)
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+
+	// 진행 중인 예약이 있으면 거부
+	s.as("ps@example.com")
+	_, err := s.invoke("delete_petsitter", "ps@example.com")
+	expectErr(t, err, "delete with an active booking")
+
+	s.as("cs@example.com")
+	s.mustInvoke(t, "cancel_booking", psid, userID(s, "cs@example.com"), tid)
+	s.as("ps@example.com")
+	s.mustInvoke(t, "delete_petsitter", "ps@example.com")
+	if existState(s, homeKey(psid, "cottage")) || len(homeIDs(s, psid)) != 0 {
+		t.Fatal("homes not deleted with the petsitter")
+	}
+}
+
+func TestSearchOrphans(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+	s.begin()
+	s.PutState(psid+"#t", []byte(`"`+psid+`#x#y/"`))
+	s.end()
+
+	ret, err := s.query("search_orphans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var orphans []Orphan
+	json.Unmarshal(ret, &orphans)
+	found := false
+	for _, v := range orphans {
+		if v.Key == psid+"#t" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("orphans = %+v", orphans)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 인증서 속성, 메타데이터, 트랜잭션 시각을 지정할 수 있는 MockStub
+// 실패한 Invoke 는 실제 피어처럼 쓰기를 되돌린다.
+type testStub struct {
+	*shim.MockStub
+	attrs map[string]string
+	meta  []byte
+	now   time.Time
+	txID  string
+	txNum int
+}
+
+func newTestStub(t *testing.T) *testStub {
+	uidSecret = []byte("test-secret")
+	s := &testStub{MockStub: shim.NewMockStub("ps", new(PS)), attrs: map[string]string{}}
+	s.now = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	s.begin()
+	defer s.end()
+	if _, err := new(PS).Init(s, "init", nil); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (s *testStub) GetTxID() string { return s.txID }
+
+func (s *testStub) ReadCertAttribute(name string) ([]byte, error) {
+	v, ok := s.attrs[name]
+	if !ok {
+		return nil, errors.New("no attribute " + name)
+	}
+	return []byte(v), nil
+}
+
+func (s *testStub) GetCallerMetadata() ([]byte, error) { return s.meta, nil }
+
+func (s *testStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.now.Unix(), Nanos: int32(s.now.Nanosecond())}, nil
+}
+
+// 이후 호출의 인증서 email 속성 ("" 이면 속성 없음)
+func (s *testStub) as(email string) *testStub {
+	s.attrs = map[string]string{}
+	if email != "" {
+		s.attrs["email"] = email
+	}
+	return s
+}
+
+// 이후 호출을 관리자로
+func (s *testStub) admin() *testStub {
+	s.attrs = map[string]string{"email": "admin@example.com", "role": "admin"}
+	return s
+}
+
+func (s *testStub) begin() {
+	s.txNum++
+	s.txID = "tx" + strconv.Itoa(s.txNum)
+	s.MockTransactionStart(s.txID)
+}
+
+func (s *testStub) end() {
+	s.MockTransactionEnd(s.txID)
+}
+
+func (s *testStub) invoke(function string, args ...string) ([]byte, error) {
+	before := map[string][]byte{}
+	for k, v := range s.State {
+		before[k] = v
+	}
+	ccstr := CCstr
+	s.begin()
+	defer s.end()
+	ret, err := new(PS).Invoke(s, function, args)
+	if err != nil {
+		for k := range s.State {
+			if _, ok := before[k]; !ok {
+				s.DelState(k)
+			}
+		}
+		for k, v := range before {
+			s.PutState(k, v)
+		}
+		CCstr = ccstr
+	}
+	return ret, err
+}
+
+func (s *testStub) query(function string, args ...string) ([]byte, error) {
+	return new(PS).Query(s, function, args)
+}
+
+func (s *testStub) mustInvoke(t *testing.T, function string, args ...string) []byte {
+	t.Helper()
+	ret, err := s.invoke(function, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", function, err)
+	}
+	return ret
+}
+
+// 펫시터 등록 후 ID 반환
+func (s *testStub) petsitter(t *testing.T, email string) string {
+	t.Helper()
+	s.as(email)
+	return string(s.mustInvoke(t, "save_petsitter", email, "nick", "30000", "20000", "10000", "20240101", "20241231", "", "3", "1", "1", "1", "apt", "info"))
+}
+
+// 고객이 TS 30일 전에 예약한 거래 ID 반환 (TS, TE: YYYYMMDD)
+func (s *testStub) trade(t *testing.T, psid, consumer, ts, te string) string {
+	t.Helper()
+	now := s.now
+	defer func() { s.now = now }()
+	start, _ := parseDate(ts)
+	s.now = start.Add(-30 * 24 * time.Hour)
+	s.as(consumer)
+	return string(s.mustInvoke(t, "save_tran", psid, "nick", consumer, ts, te, te, "100000", "booked"))
+}
+
+// 펫시터가 수락한 거래 ID 반환
+func (s *testStub) acceptedTrade(t *testing.T, petsitter, consumer, ts, te string) string {
+	t.Helper()
+	psid := userID(s, petsitter)
+	tid := s.trade(t, psid, consumer, ts, te)
+	now := s.now
+	defer func() { s.now = now }()
+	start, _ := parseDate(ts)
+	s.now = start.Add(-24 * time.Hour)
+	s.as(petsitter)
+	s.mustInvoke(t, "accept_booking", psid, userID(s, consumer), tid)
+	return tid
+}
+
+func expectErr(t *testing.T, err error, name string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("%s: expected an error", name)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// JSON merge-patch (RFC 7396)
+// 값이 있는 필드는 변경, null 인 필드는 비우고, 없는 필드는 그대로 둔다.
+// 모든 필드가 문자열이므로 숫자, true/false 는 JSON 표기 그대로 문자열로 저장한다.
+type patch map[string]*string
+
+func parsePatch(s string) (patch, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, errors.New("Invalid patch: " + err.Error())
+	}
+	p := patch{}
+	for name, v := range raw {
+		var val interface{}
+		if err := json.Unmarshal(v, &val); err != nil {
+			return nil, errors.New("Invalid patch: " + err.Error())
+		}
+		switch val := val.(type) {
+		case nil:
+			p[name] = nil
+		case string:
+			p[name] = &val
+		case float64, bool:
+			str := string(v)
+			p[name] = &str
+		default:
+			return nil, errors.New("Invalid patch: " + name + " is not a string, number or boolean")
+		}
+	}
+	return p, nil
+}
+
+// 기존 함수의 인자를 patch 로 (skipNone 이면 none 은 변경하지 않음)
+func argsPatch(names, vals []string, skipNone bool) patch {
+	p := patch{}
+	for i, name := range names {
+		if !skipNone || vals[i] != "none" {
+			v := vals[i]
+			p[name] = &v
+		}
+	}
+	return p
+}
+
+func (p patch) has(names ...string) bool {
+	for _, name := range names {
+		if _, ok := p[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// patch 에 있는 필드만
+func (p patch) pick(fields map[string]*string) map[string]*string {
+	ret := map[string]*string{}
+	for name, v := range fields {
+		if _, ok := p[name]; ok {
+			ret[name] = v
+		}
+	}
+	return ret
+}
+
+func (p patch) apply(fields map[string]*string) error {
+	var names []string
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fields[name] == nil {
+			return errors.New("Not patchable field: " + name)
+		}
+	}
+	for _, name := range names {
+		if p[name] == nil {
+			*fields[name] = ""
+		} else {
+			*fields[name] = *p[name]
+		}
+	}
+	return nil
+}
+
+func homeFields(h *HomeAsset) map[string]*string {
+	return map[string]*string{"Name": &h.Name, "State": &h.State, "City": &h.City, "Street": &h.Street, "Adt": &h.Adt, "Code": &h.Code,
+		"Type": &h.Type, "Room": &h.Room, "Elevator": &h.Elevator, "Parking": &h.Parking, "Lat": &h.Lat, "Lng": &h.Lng}
+}
+
+func petsitterFields(p *Petsitter) map[string]*string {
+	return map[string]*string{"Nickname": &p.Nickname, "CostL": &p.CostL, "CostM": &p.CostM, "CostS": &p.CostS, "Start": &p.Start, "End": &p.End,
+		"Except": &p.Except, "TotalNum": &p.TotalNum, "NumL": &p.NumL, "NumM": &p.NumM, "NumS": &p.NumS, "Home": &p.Home, "HomeInfo": &p.HomeInfo}
+}
+
+// 이메일, 집 ID, JSON merge-patch [, 기대 revision] (집이 없으면 새로 만든다)
+// 새 revision 을 반환한다.
+func (t *PS) patch_home(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 && len(args) != 4 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                            <<<< Home Patch >>>>")
+		fmt.Println("              Incorrect number of arguments. Expecting 3 or 4")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home PATCH] Incorrect number of arguments. Expecting 3 or 4")
+	}
+	p, err := parsePatch(args[2])
+	if err != nil {
+		return nil, errors.New("[Home PATCH] " + err.Error())
+	}
+	rev, err := patchHome(stub, args[0], args[1], p, optArg(args, 3))
+	if err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                            <<<< Home Patch >>>>")
+		fmt.Println("                              Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Home PATCH] " + err.Error())
+	}
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Home Patch chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return []byte(rev), nil
+}
+
+// 이메일, JSON merge-patch [, 기대 revision]
+// 새 revision 을 반환한다.
+func (t *PS) patch_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 2 && len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Petsitter Patch >>>>")
+		fmt.Println("              Incorrect number of arguments. Expecting 2 or 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter PATCH] Incorrect number of arguments. Expecting 2 or 3")
+	}
+	p, err := parsePatch(args[1])
+	if err != nil {
+		return nil, errors.New("[Petsitter PATCH] " + err.Error())
+	}
+	rev, err := patchPetsitter(stub, args[0], p, optArg(args, 2))
+	if err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Petsitter Patch >>>>")
+		fmt.Println("                              Patch failed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter PATCH] " + err.Error())
+	}
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                <<<< Petsitter Patch chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return []byte(rev), nil
+}
+
+// 집과 상세 주소, 집 ID 목록, 위치 인덱스를 함께 갱신하고 새 revision 을 반환
+// 본인 또는 관리자만 (patch_home 과 기존 save_home*, modify_home* 모두 여기를 거친다)
+func patchHome(stub shim.ChaincodeStubInterface, email, hid string, p patch, expected string) (string, error) {
+	if !selfOrAdmin(stub, email) {
+		return "", errors.New("Not allowed caller")
+	}
+	if hid == "" || strings.ContainsAny(hid, "#/") {
+		return "", errors.New("Invalid home ID: " + hid)
+	}
+	confUser, _ := stub.GetState(email)
+	if confUser == nil || petsitterDeleted(confUser) {
+		return "", errors.New("Not exist Petsitter")
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return "", err
+	}
+	homeAsset := HomeAsset{}
+	conf, _ := stub.GetState(homeKey(email, hid))
+	json.Unmarshal(conf, &homeAsset)
+	if err := checkRevision(homeAsset.Revision, expected); err != nil {
+		return "", err
+	}
+	loadHomeAddress(stub, email, hid, &homeAsset, nil)
+	oldGeo := geoKey(email, hid, homeAsset)
+	if err := p.apply(homeFields(&homeAsset)); err != nil {
+		return "", err
+	}
+	// 주소를 넣어 새 집을 만들면 생성과 같이 전체 주소를 확인한다
+	// (기존 save_home_room 등은 주소보다 먼저 호출될 수 있다)
+	if conf == nil && p.has("State", "City", "Street", "Adt", "Code") {
+		err = normalizeAddress(stub, &homeAsset)
+	} else {
+		err = normalizePatchedAddress(stub, &homeAsset, p)
+	}
+	if err != nil {
+		return "", err
+	}
+	if homeAsset.Lat != "" || homeAsset.Lng != "" {
+		lat, err1 := strconv.ParseFloat(homeAsset.Lat, 64)
+		lng, err2 := strconv.ParseFloat(homeAsset.Lng, 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return "", errors.New("Invalid latitude/longitude")
+		}
+	}
+	homeAsset.ID = hid
+	homeAsset.SaveTime = now.String()
+	if homeAsset.Street == "" && homeAsset.Adt == "" && homeAsset.Code == "" {
+		stub.DelState(addrKey(email, hid))
+	}
+	if err := putHomeAddress(stub, email, hid, &homeAsset, p); err != nil {
+		return "", err
+	}
+	jsonAsBytes, _ := marshalDoc(&homeAsset)
+	stub.PutState(homeKey(email, hid), jsonAsBytes)
+	addHomeIndex(stub, email, hid)
+	if newGeo := geoKey(email, hid, homeAsset); newGeo != oldGeo {
+		if oldGeo != "" {
+			stub.DelState(oldGeo)
+		}
+		addGeoIndex(stub, email, hid, homeAsset)
+	}
+	return homeAsset.Revision, nil
+}
+
+// patch 에 있는 주소 필드만 표준화한다.
+// 바꾸지 않은 상세 주소는 암호문 그대로일 수 있으므로 건드리지 않는다.
+func normalizePatchedAddress(stub shim.ChaincodeStubInterface, homeAsset *HomeAsset, p patch) error {
+	if p.has("Street") {
+		homeAsset.Street = strings.Join(strings.Fields(homeAsset.Street), " ")
+	}
+	if p.has("Adt") {
+		homeAsset.Adt = strings.Join(strings.Fields(homeAsset.Adt), " ")
+	}
+	if p.has("Code") {
+		homeAsset.Code = strings.TrimSpace(homeAsset.Code)
+	}
+	if !p.has("State", "City", "Code") {
+		return nil
+	}
+	region, ok := findRegion(loadRegions(stub), homeAsset.State)
+	if !ok {
+		return errors.New("Unknown state: " + homeAsset.State)
+	}
+	homeAsset.State = region.Name
+	if p.has("State", "City") {
+		city, err := normalizeCity(region, homeAsset.City)
+		if err != nil {
+			return err
+		}
+		homeAsset.City = city
+	}
+	if p.has("Code") {
+		return checkPostalCode(region, homeAsset.Code)
+	}
+	return nil
+}
+
+// 본인 또는 관리자만 (patch_petsitter, modify_petsitter)
+func patchPetsitter(stub shim.ChaincodeStubInterface, email string, p patch, expected string) (string, error) {
+	if !selfOrAdmin(stub, email) {
+		return "", errors.New("Not allowed caller")
+	}
+	confUser, _ := stub.GetState(email)
+	if confUser == nil || petsitterDeleted(confUser) {
+		return "", errors.New("Not exist Petsitter")
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return "", err
+	}
+	petsitter := Petsitter{}
+	json.Unmarshal(confUser, &petsitter)
+	if err := checkRevision(petsitter.Revision, expected); err != nil {
+		return "", err
+	}
+	if err := p.apply(petsitterFields(&petsitter)); err != nil {
+		return "", err
+	}
+	petsitter.SaveTime = now.String()
+	key, err := piiKey(stub)
+	if err == nil {
+		err = encryptFields(stub, key, email, p.pick(petsitterPII(&petsitter)))
+	}
+	if err != nil {
+		return "", err
+	}
+	jsonAsBytes, _ := marshalDoc(&petsitter)
+	stub.PutState(email, jsonAsBytes)
+	return petsitter.Revision, nil
+}
+
+// 선택 인자 (없으면 "")
+func optArg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
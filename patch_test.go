@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func readHome(s *testStub, id, hid string) HomeAsset {
+	homeAsset := HomeAsset{}
+	valAsbytes, _ := s.GetState(homeKey(id, hid))
+	json.Unmarshal(valAsbytes, &homeAsset)
+	return homeAsset
+}
+
+func TestParsePatchScalars(t *testing.T) {
+	p, err := parsePatch(`{"CostL":35000,"Elevator":true,"Name":"집","Adt":null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *p["CostL"] != "35000" || *p["Elevator"] != "true" || *p["Name"] != "집" || p["Adt"] != nil {
+		t.Fatalf("patch = %v", p)
+	}
+	if _, err := parsePatch(`{"Room":[1,2]}`); err == nil {
+		t.Fatal("array value accepted")
+	}
+}
+
+func TestPatchCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.petsitter(t, "other@example.com")
+
+	s.as("other@example.com")
+	_, err := s.invoke("patch_petsitter", "ps@example.com", `{"CostL":1}`)
+	expectErr(t, err, "patch_petsitter by another user")
+	_, err = s.invoke("patch_home", "ps@example.com", "cottage", `{"State":"서울","City":"강남","Code":"06234"}`)
+	expectErr(t, err, "patch_home by another user")
+	if existState(s, homeKey(psid, "cottage")) {
+		t.Fatal("home created by another user")
+	}
+
+	s.as("ps@example.com")
+	s.mustInvoke(t, "patch_home", "ps@example.com", "cottage", `{"State":"서울","City":"강남","Code":"06234","Room":3}`)
+	if got := readHome(s, psid, "cottage"); got.City != "강남구" || got.Room != "3" {
+		t.Fatalf("home = %+v", got)
+	}
+}
+
+func TestPatchHomeKeepsEncryptedCode(t *testing.T) {
+	s := newTestStub(t)
+	s.meta = testKeyMeta()
+	psid := s.petsitter(t, "ps@example.com")
+	s.mustInvoke(t, "patch_home", "ps@example.com", "cottage", `{"State":"서울","City":"강남","Street":"테헤란로 1","Code":"06234"}`)
+	addr, _ := s.GetState(addrKey(psid, "cottage"))
+	if !strings.Contains(string(addr), encPrefix) {
+		t.Fatalf("address not encrypted: %s", addr)
+	}
+
+	// 키 없이 시/군/구만 변경: 암호화된 우편번호는 검사하지 않고 그대로 둔다
+	s.meta = nil
+	s.mustInvoke(t, "patch_home", "ps@example.com", "cottage", `{"City":"서초"}`)
+	if got := readHome(s, psid, "cottage"); got.City != "서초구" {
+		t.Fatalf("home = %+v", got)
+	}
+	if after, _ := s.GetState(addrKey(psid, "cottage")); string(after) != string(addr) {
+		t.Fatalf("address changed: %s", after)
+	}
+
+	_, err := s.invoke("patch_home", "ps@example.com", "cottage", `{"Code":"99999"}`)
+	expectErr(t, err, "postal code outside the state")
+}
+
+func TestPatchRevisionConflict(t *testing.T) {
+	s := newTestStub(t)
+	s.petsitter(t, "ps@example.com")
+	s.as("ps@example.com")
+	rev := string(s.mustInvoke(t, "patch_petsitter", "ps@example.com", `{"CostL":35000}`, "1"))
+	if rev != "2" {
+		t.Fatalf("revision = %s", rev)
+	}
+	_, err := s.invoke("patch_petsitter", "ps@example.com", `{"CostL":36000}`, "1")
+	expectErr(t, err, "patch with a stale revision")
+	s.mustInvoke(t, "patch_petsitter", "ps@example.com", `{"CostL":36000}`, rev)
+}
+
+func TestLegacyModifyCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.mustInvoke(t, "patch_home", "ps@example.com", "cottage", `{"State":"서울","City":"강남","Code":"06234"}`)
+	none := func(n int) []string {
+		ret := make([]string, n)
+		for i := range ret {
+			ret[i] = "none"
+		}
+		return ret
+	}
+
+	s.as("other@example.com")
+	args := append([]string{"ps@example.com", "hacked"}, none(12)...)
+	_, err := s.invoke("modify_petsitter", args...)
+	expectErr(t, err, "modify_petsitter by another user")
+	args = append([]string{"ps@example.com", "cottage", "hacked"}, none(9)...)
+	_, err = s.invoke("modify_named_home", args...)
+	expectErr(t, err, "modify_named_home by another user")
+	if got := readHome(s, psid, "cottage"); got.Name == "hacked" {
+		t.Fatalf("home = %+v", got)
+	}
+
+	s.admin()
+	s.mustInvoke(t, "modify_named_home", args...)
+	if got := readHome(s, psid, "cottage"); got.Name != "hacked" {
+		t.Fatalf("home = %+v", got)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 상세 주소는 집 레코드와 분리하여 저장하고, 예약이 수락된 고객에게만 공개한다.
+// Fabric 0.6 에는 private data collection 이 없으므로 공개 범위는 체인코드 조회 함수에서 제한한다.
+//
+// 한계: 상세 주소는 save_home*, modify_home*, save_named_home, modify_named_home, patch_home,
+// register_petsitter, bulk_import 의 Invoke 인자로 평문 전달되고, Fabric 0.6 은 인자를 블록의
+// 트랜잭션에 그대로 기록한다. 월드 상태 조회는 막지만 블록을 읽을 수 있으면 주소가 보이므로
+// 이 요구사항은 일부만 충족한다 (README 참고).
+type HomeAddress struct { // Precise address of a home (KEY: User ID#addr#집 ID)
+	Street        string
+	Adt           string
+	Code          string
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
+}
+
+// PSID, CSID, 거래 ID (펫시터 본인)
+func (t *PS) accept_booking(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Accept >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking ACCEPT] Incorrect number of arguments. Expecting 3")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	valAsbytes, _ := stub.GetState(key)
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Accept >>>>")
+		fmt.Println("                           Not exist transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking ACCEPT] Not exist transaction")
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	if userID(stub, callerAttr(stub, "email")) != tradeRec.PSID {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Accept >>>>")
+		fmt.Println("                        Caller is not the petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking ACCEPT] Caller is not the petsitter")
+	}
+	if tradeRec.Status != "" && tradeRec.Status != "booked" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Booking Accept >>>>")
+		fmt.Println("                          Not acceptable booking")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Booking ACCEPT] Not acceptable booking: " + tradeRec.Status)
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Booking ACCEPT] " + err.Error())
+	}
+	tradeRec.Status = "accepted"
+	appendHistory(&tradeRec, "accepted@"+now.Format("20060102"))
+	jsonAsBytes, _ := marshalDoc(&tradeRec)
+	stub.PutState(key, jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Booking Accept chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// PSID, CSID, 거래 ID (예약한 고객 또는 펫시터만 조회 가능)
+func (t *PS) read_home_address(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Address Read >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Address QUERY] Incorrect number of arguments. Expecting 3")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	valAsbytes, _ := stub.GetState(key)
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Address Read >>>>")
+		fmt.Println("                           Not exist transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[Address QUERY] Not exist transaction")
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	caller := userID(stub, callerAttr(stub, "email"))
+	if caller != tradeRec.CSID && caller != tradeRec.PSID {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Address Read >>>>")
+		fmt.Println("                         Not a party of the trade")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[Address QUERY] Not a party of the trade")
+	}
+	if tradeRec.Status != "accepted" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Address Read >>>>")
+		fmt.Println("                           Not accepted booking")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[Address QUERY] Not accepted booking")
+	}
+	homeAsset := HomeAsset{}
+	confh, _ := stub.GetState(homeKey(tradeRec.PSID, tradeRec.HomeID))
+	json.Unmarshal(confh, &homeAsset)
+	encKey, _ := piiKey(stub)
+	loadHomeAddress(stub, tradeRec.PSID, tradeRec.HomeID, &homeAsset, encKey)
+	return json.Marshal(HomeAddress{Street: homeAsset.Street, Adt: homeAsset.Adt, Code: homeAsset.Code})
+}
+
+func addrKey(email, hid string) string {
+	if hid == "" {
+		hid = defaultHome
+	}
+	return email + "#addr#" + hid
+}
+
+// key 가 nil 이면 저장된 값(암호문) 그대로
+func loadHomeAddress(stub shim.ChaincodeStubInterface, email, hid string, homeAsset *HomeAsset, key []byte) {
+	conf, _ := stub.GetState(addrKey(email, hid))
+	if conf == nil {
+		return
+	}
+	addr := HomeAddress{}
+	json.Unmarshal(conf, &addr)
+	decryptFields(key, addrKey(email, hid), addressPII(&addr))
+	homeAsset.Street = addr.Street
+	homeAsset.Adt = addr.Adt
+	homeAsset.Code = addr.Code
+}
+
+// 상세 주소를 분리 저장하고 집 레코드에서는 비운다
+// changed 가 nil 이 아니면 그 안의 필드만 입력 값으로 보고 암호화한다 (나머지는 저장된 값).
+func putHomeAddress(stub shim.ChaincodeStubInterface, email, hid string, homeAsset *HomeAsset, changed patch) error {
+	if homeAsset.Street == "" && homeAsset.Adt == "" && homeAsset.Code == "" {
+		return nil
+	}
+	key, err := piiKey(stub)
+	if err != nil {
+		return err
+	}
+	addr := HomeAddress{Street: homeAsset.Street, Adt: homeAsset.Adt, Code: homeAsset.Code}
+	fields := addressPII(&addr)
+	if changed != nil {
+		fields = changed.pick(fields)
+	}
+	if err := encryptFields(stub, key, addrKey(email, hid), fields); err != nil {
+		return err
+	}
+	jsonAsBytes, _ := marshalDoc(&addr)
+	stub.PutState(addrKey(email, hid), jsonAsBytes)
+	*homeAsset = publicHome(*homeAsset)
+	return nil
+}
+
+// 공개 조회용 (시/도, 시/군/구까지만)
+func publicHome(homeAsset HomeAsset) HomeAsset {
+	homeAsset.Street = ""
+	homeAsset.Adt = ""
+	homeAsset.Code = ""
+	return homeAsset
+}
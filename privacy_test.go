@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAcceptBookingCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+	csid := userID(s, "cs@example.com")
+
+	for _, caller := range []string{"cs@example.com", "other@example.com"} {
+		s.as(caller)
+		_, err := s.invoke("accept_booking", psid, csid, tid)
+		expectErr(t, err, "accept by "+caller)
+	}
+	s.as("cs@example.com")
+	_, err := s.query("read_home_address", psid, csid, tid)
+	expectErr(t, err, "address of a booking that is not accepted")
+
+	s.as("ps@example.com")
+	s.mustInvoke(t, "accept_booking", psid, csid, tid)
+}
+
+func TestMigrateLegacyHomeAddress(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.begin()
+	s.PutState(homeKey(psid, defaultHome), []byte(`{"ID":"default","State":"서울특별시","City":"강남구","Street":"테헤란로 1","Adt":"101호","Code":"06236"}`))
+	s.end()
+
+	s.admin()
+	s.mustInvoke(t, "migrate_schema", "", "100")
+	homeAsset := HomeAsset{}
+	conf, _ := s.GetState(homeKey(psid, defaultHome))
+	json.Unmarshal(conf, &homeAsset)
+	if homeAsset.Street != "" || homeAsset.Adt != "" || homeAsset.Code != "" || homeAsset.City != "강남구" {
+		t.Fatalf("public home = %+v", homeAsset)
+	}
+
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+	csid := userID(s, "cs@example.com")
+	s.as("ps@example.com")
+	s.mustInvoke(t, "accept_booking", psid, csid, tid)
+	s.as("cs@example.com")
+	ret, err := s.query("read_home_address", psid, csid, tid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := HomeAddress{}
+	json.Unmarshal(ret, &addr)
+	if addr.Street != "테헤란로 1" || addr.Adt != "101호" || addr.Code != "06236" {
+		t.Fatalf("address = %+v", addr)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+type Region struct { // Region code table entry (KEY: _regions)
+	Code    string   // Region code
+	Name    string   // Canonical state name
+	Aliases []string // Other spellings of the state
+	Postal  string   // Postal code prefix ranges "01-09,..."
+	Cities  []string // Canonical city/county/district names (not checked if empty)
+}
+
+// 시/도 코드 테이블 기본값 (Init 시 저장)
+var defaultRegions = []Region{
+	{"11", "서울특별시", []string{"서울", "서울시", "Seoul"}, "01-09", []string{"종로구", "중구", "용산구", "성동구", "광진구", "동대문구", "중랑구", "성북구", "강북구", "도봉구", "노원구", "은평구", "서대문구", "마포구", "양천구", "강서구", "구로구", "금천구", "영등포구", "동작구", "관악구", "서초구", "강남구", "송파구", "강동구"}},
+	{"26", "부산광역시", []string{"부산", "부산시", "Busan"}, "46-49", []string{"중구", "서구", "동구", "영도구", "부산진구", "동래구", "남구", "북구", "해운대구", "사하구", "금정구", "강서구", "연제구", "수영구", "사상구", "기장군"}},
+	{"27", "대구광역시", []string{"대구", "대구시", "Daegu"}, "41-43", []string{"중구", "동구", "서구", "남구", "북구", "수성구", "달서구", "달성군", "군위군"}},
+	{"28", "인천광역시", []string{"인천", "인천시", "Incheon"}, "21-23", []string{"중구", "동구", "미추홀구", "연수구", "남동구", "부평구", "계양구", "서구", "강화군", "옹진군"}},
+	{"29", "광주광역시", []string{"광주", "광주시", "Gwangju"}, "61-62", []string{"동구", "서구", "남구", "북구", "광산구"}},
+	{"30", "대전광역시", []string{"대전", "대전시", "Daejeon"}, "34-35", []string{"동구", "중구", "서구", "유성구", "대덕구"}},
+	{"31", "울산광역시", []string{"울산", "울산시", "Ulsan"}, "44-45", []string{"중구", "남구", "동구", "북구", "울주군"}},
+	{"36", "세종특별자치시", []string{"세종", "세종시", "Sejong"}, "30", nil},
+	{"41", "경기도", []string{"경기", "Gyeonggi", "Gyeonggi-do"}, "10-20", []string{"수원시", "성남시", "의정부시", "안양시", "부천시", "광명시", "평택시", "동두천시", "안산시", "고양시", "과천시", "구리시", "남양주시", "오산시", "시흥시", "군포시", "의왕시", "하남시", "용인시", "파주시", "이천시", "안성시", "김포시", "화성시", "광주시", "양주시", "포천시", "여주시", "연천군", "가평군", "양평군"}},
+	{"42", "강원도", []string{"강원특별자치도", "강원", "Gangwon", "Gangwon-do"}, "24-26", []string{"춘천시", "원주시", "강릉시", "동해시", "태백시", "속초시", "삼척시", "홍천군", "횡성군", "영월군", "평창군", "정선군", "철원군", "화천군", "양구군", "인제군", "고성군", "양양군"}},
+	{"43", "충청북도", []string{"충북", "Chungbuk", "Chungcheongbuk-do"}, "27-29", []string{"청주시", "충주시", "제천시", "보은군", "옥천군", "영동군", "증평군", "진천군", "괴산군", "음성군", "단양군"}},
+	{"44", "충청남도", []string{"충남", "Chungnam", "Chungcheongnam-do"}, "31-33", []string{"천안시", "공주시", "보령시", "아산시", "서산시", "논산시", "계룡시", "당진시", "금산군", "부여군", "서천군", "청양군", "홍성군", "예산군", "태안군"}},
+	{"45", "전라북도", []string{"전북특별자치도", "전북", "Jeonbuk", "Jeollabuk-do"}, "54-56", []string{"전주시", "군산시", "익산시", "정읍시", "남원시", "김제시", "완주군", "진안군", "무주군", "장수군", "임실군", "순창군", "고창군", "부안군"}},
+	{"46", "전라남도", []string{"전남", "Jeonnam", "Jeollanam-do"}, "57-60", []string{"목포시", "여수시", "순천시", "나주시", "광양시", "담양군", "곡성군", "구례군", "고흥군", "보성군", "화순군", "장흥군", "강진군", "해남군", "영암군", "무안군", "함평군", "영광군", "장성군", "완도군", "진도군", "신안군"}},
+	{"47", "경상북도", []string{"경북", "Gyeongbuk", "Gyeongsangbuk-do"}, "36-40", []string{"포항시", "경주시", "김천시", "안동시", "구미시", "영주시", "영천시", "상주시", "문경시", "경산시", "의성군", "청송군", "영양군", "영덕군", "청도군", "고령군", "성주군", "칠곡군", "예천군", "봉화군", "울진군", "울릉군"}},
+	{"48", "경상남도", []string{"경남", "Gyeongnam", "Gyeongsangnam-do"}, "50-53", []string{"창원시", "진주시", "통영시", "사천시", "김해시", "밀양시", "거제시", "양산시", "의령군", "함안군", "창녕군", "고성군", "남해군", "하동군", "산청군", "함양군", "거창군", "합천군"}},
+	{"50", "제주특별자치도", []string{"제주", "제주도", "Jeju", "Jeju-do"}, "63", []string{"제주시", "서귀포시"}},
+}
+
+// JSON 배열 (관리자)
+func (t *PS) set_regions(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Region Insert >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Region INSSERT] Incorrect number of arguments. Expecting 1")
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Region Insert >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Region INSSERT] Caller is not an admin")
+	}
+	var regions []Region
+	if err := json.Unmarshal([]byte(args[0]), &regions); err != nil || len(regions) == 0 {
+		return nil, errors.New("[Region INSSERT] Invalid region table")
+	}
+	for _, v := range regions {
+		if v.Code == "" || v.Name == "" {
+			return nil, errors.New("[Region INSSERT] Empty region code or name")
+		}
+		if _, err := parsePostalRanges(v.Postal); err != nil {
+			return nil, errors.New("[Region INSSERT] " + v.Code + ": " + err.Error())
+		}
+	}
+	jsonAsBytes, _ := json.Marshal(regions)
+	stub.PutState("_regions", jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Region Insert chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+func (t *PS) read_regions(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 0 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Region Read >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 0")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Region QUERY] Incorrect number of arguments. Expecting 0")
+	}
+	return json.Marshal(loadRegions(stub))
+}
+
+func loadRegions(stub shim.ChaincodeStubInterface) []Region {
+	valAsbytes, _ := stub.GetState("_regions")
+	if valAsbytes == nil {
+		return defaultRegions
+	}
+	var regions []Region
+	json.Unmarshal(valAsbytes, &regions)
+	return regions
+}
+
+// 시/도 -> 테이블의 표준 이름
+func findRegion(regions []Region, state string) (Region, bool) {
+	key := normalizeText(state)
+	for _, v := range regions {
+		if normalizeText(v.Name) == key || v.Code == key {
+			return v, true
+		}
+		for _, alias := range v.Aliases {
+			if normalizeText(alias) == key {
+				return v, true
+			}
+		}
+	}
+	return Region{}, false
+}
+
+// 테이블에 없는 값은 공백만 정리
+func canonicalState(regions []Region, state string) string {
+	if region, ok := findRegion(regions, state); ok {
+		return region.Name
+	}
+	return strings.Join(strings.Fields(state), " ")
+}
+
+// 시/도, 시/군/구 표준화 및 우편번호 검증
+func normalizeAddress(stub shim.ChaincodeStubInterface, homeAsset *HomeAsset) error {
+	region, ok := findRegion(loadRegions(stub), homeAsset.State)
+	if !ok {
+		return errors.New("Unknown state: " + homeAsset.State)
+	}
+	homeAsset.State = region.Name
+	city, err := normalizeCity(region, homeAsset.City)
+	if err != nil {
+		return err
+	}
+	homeAsset.City = city
+	homeAsset.Street = strings.Join(strings.Fields(homeAsset.Street), " ")
+	homeAsset.Adt = strings.Join(strings.Fields(homeAsset.Adt), " ")
+	homeAsset.Code = strings.TrimSpace(homeAsset.Code)
+	return checkPostalCode(region, homeAsset.Code)
+}
+
+// 숫자 5자리이고 앞 두 자리가 시/도의 우편번호 범위에 있어야 한다
+func checkPostalCode(region Region, code string) error {
+	if len(code) != 5 {
+		return errors.New("Postal code must be 5 digits: " + code)
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return errors.New("Postal code must be 5 digits: " + code)
+		}
+	}
+	prefix, _ := strconv.Atoi(code[:2])
+	ranges, _ := parsePostalRanges(region.Postal)
+	for _, r := range ranges {
+		if prefix >= r[0] && prefix <= r[1] {
+			return nil
+		}
+	}
+	return errors.New("Postal code " + code + " is not in " + region.Name)
+}
+
+// "01-09,10" -> [[1 9] [10 10]]
+func parsePostalRanges(s string) ([][2]int, error) {
+	var ret [][2]int
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		kv := strings.Split(v, "-")
+		from, err := strconv.Atoi(kv[0])
+		if err != nil {
+			return nil, errors.New("bad postal range " + v)
+		}
+		to := from
+		if len(kv) == 2 {
+			if to, err = strconv.Atoi(kv[1]); err != nil {
+				return nil, errors.New("bad postal range " + v)
+			}
+		} else if len(kv) > 2 {
+			return nil, errors.New("bad postal range " + v)
+		}
+		ret = append(ret, [2]int{from, to})
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("no postal range")
+	}
+	return ret, nil
+}
+
+// 소문자, 앞뒤 공백 제거, 연속 공백 하나로
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// 시/군/구 -> 테이블의 표준 이름 ("강남", "강남구" -> "강남구")
+// 첫 단어만 비교하고 나머지(성남시 분당구의 분당구 등)는 공백만 정리한다.
+func normalizeCity(region Region, city string) (string, error) {
+	words := strings.Fields(city)
+	if len(words) == 0 || len(region.Cities) == 0 {
+		return strings.Join(words, " "), nil
+	}
+	key := strings.ToLower(words[0])
+	for _, v := range region.Cities {
+		name := strings.ToLower(v)
+		r := []rune(name)
+		if key == name || (len(r) > 1 && key == string(r[:len(r)-1])) {
+			words[0] = v
+			return strings.Join(words, " "), nil
+		}
+	}
+	return "", errors.New("Unknown city: " + city + " in " + region.Name)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	s := newTestStub(t)
+	cases := []struct {
+		state, city, code string
+		wantState         string
+		wantCity          string
+		ok                bool
+	}{
+		{"seoul ", " 강남 ", "06236", "서울특별시", "강남구", true},
+		{"서울", "강남구", "06236", "서울특별시", "강남구", true},
+		{"경기", "성남시  분당구", "13494", "경기도", "성남시 분당구", true},
+		{"세종", "조치원읍", "30000", "세종특별자치시", "조치원읍", true},
+		{"강원특별자치도", "춘천", "24341", "강원도", "춘천시", true},
+		{"전북특별자치도", "전주시", "54999", "전라북도", "전주시", true},
+		{"서울", "강남구", "", "", "", false},
+		{"서울", "해운대구", "06236", "", "", false},
+		{"서울", "강남구", "+6236", "", "", false},
+		{"서울", "강남구", "0623 ", "", "", false},
+		{"서울", "강남구", "48058", "", "", false},
+	}
+	for _, c := range cases {
+		homeAsset := HomeAsset{State: c.state, City: c.city, Code: c.code}
+		err := normalizeAddress(s, &homeAsset)
+		if (err == nil) != c.ok {
+			t.Fatalf("%+v: err = %v", c, err)
+		}
+		if c.ok && (homeAsset.State != c.wantState || homeAsset.City != c.wantCity) {
+			t.Fatalf("%+v: got %s %s", c, homeAsset.State, homeAsset.City)
+		}
+	}
+}
+
+// 생성과 같은 규칙: 우편번호를 비우는 patch 도 거부한다
+func TestPatchPostalCodeRule(t *testing.T) {
+	s := newTestStub(t)
+	s.petsitter(t, "ps@example.com")
+	s.mustInvoke(t, "patch_home", "ps@example.com", "cottage", `{"State":"서울","City":"강남","Code":"06234"}`)
+	for _, code := range []string{`""`, "null", `"6234"`} {
+		_, err := s.invoke("patch_home", "ps@example.com", "cottage", `{"Code":`+code+`}`)
+		expectErr(t, err, "patch Code "+code)
+	}
+	_, err := s.invoke("patch_home", "ps@example.com", "villa", `{"State":"서울","City":"강남"}`)
+	expectErr(t, err, "new home without a postal code")
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const bulkLimit = 200 // Maximum records per bulk_import call
+
+type Registration struct { // bulk_import record
+	Email     string
+	Petsitter Petsitter  // Nickname ~ HomeInfo (Status, rating fields are ignored)
+	Home      *HomeAsset // Default home (optional, Street/Adt/Code stored in the addr record)
+}
+
+type ImportResult struct { // bulk_import result per record
+	Index  string
+	Email  string
+	ID     string
+	Status string // ok, invalid
+	Error  string
+}
+
+type ImportReport struct { // bulk_import error (the error message is this JSON)
+	Error   string
+	Invalid string // Number of invalid records
+	Results []ImportResult
+}
+
+// JSON 배열 (한 번에 최대 200건, 관리자)
+// 모든 레코드가 유효할 때만 저장하고, 하나라도 잘못되면 아무것도 저장하지 않는다.
+// 이때 오류 메시지는 ImportReport JSON 이므로 클라이언트가 그대로 파싱할 수 있다.
+func (t *PS) bulk_import(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                            <<<< Bulk Import >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[BULK IMPORT] Incorrect number of arguments. Expecting 1")
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                            <<<< Bulk Import >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[BULK IMPORT] Caller is not an admin")
+	}
+	var recs []Registration
+	if err := json.Unmarshal([]byte(args[0]), &recs); err != nil {
+		return nil, errors.New("[BULK IMPORT] Invalid JSON: " + err.Error())
+	}
+	if len(recs) == 0 || len(recs) > bulkLimit {
+		return nil, errors.New("[BULK IMPORT] Expecting 1 ~ " + strconv.Itoa(bulkLimit) + " records")
+	}
+
+	report := make([]ImportResult, len(recs))
+	seen := map[string]bool{}
+	invalid := 0
+	for i := range recs {
+		report[i] = ImportResult{Index: strconv.Itoa(i), Email: recs[i].Email, Status: "ok"}
+		err := validateRegistration(stub, &recs[i])
+		if err == nil && seen[strings.ToLower(strings.TrimSpace(recs[i].Email))] {
+			err = errors.New("Duplicate email in batch")
+		}
+		seen[strings.ToLower(strings.TrimSpace(recs[i].Email))] = true
+		if err != nil {
+			report[i].Status = "invalid"
+			report[i].Error = err.Error()
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                            <<<< Bulk Import >>>>")
+		fmt.Println("                             Invalid records")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		jsonAsBytes, _ := json.Marshal(ImportReport{"[BULK IMPORT] Invalid records", strconv.Itoa(invalid), report})
+		return nil, errors.New(string(jsonAsBytes))
+	}
+	for i := range recs {
+		id, err := writeRegistration(stub, &recs[i])
+		if err != nil {
+			return nil, errors.New("[BULK IMPORT] " + recs[i].Email + ": " + err.Error())
+		}
+		report[i].ID = id
+	}
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                   <<<< Bulk Import chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return json.Marshal(report)
+}
+
+// 저장 전에 확인 (집 주소는 정규화된 값으로 바뀐다)
+func validateRegistration(stub shim.ChaincodeStubInterface, rec *Registration) error {
+	if !strings.Contains(rec.Email, "@") {
+		return errors.New("Not an email: " + rec.Email)
+	}
+	if existState(stub, userID(stub, rec.Email)) {
+		return errors.New("Already exist Petsitter")
+	}
+	p := rec.Petsitter
+	if strings.TrimSpace(p.Nickname) == "" {
+		return errors.New("Nickname is required")
+	}
+	names := []string{"CostL", "CostM", "CostS", "TotalNum", "NumL", "NumM", "NumS"}
+	for i, v := range []string{p.CostL, p.CostM, p.CostS, p.TotalNum, p.NumL, p.NumM, p.NumS} {
+		if v == "" {
+			continue
+		}
+		if n, err := strconv.ParseFloat(v, 64); err != nil || n < 0 {
+			return errors.New("Invalid " + names[i] + ": " + v)
+		}
+	}
+	if err := checkAvailability(p); err != nil {
+		return err
+	}
+	if rec.Home == nil {
+		return nil
+	}
+	if err := normalizeAddress(stub, rec.Home); err != nil {
+		return err
+	}
+	if rec.Home.Lat != "" || rec.Home.Lng != "" {
+		lat, err1 := strconv.ParseFloat(rec.Home.Lat, 64)
+		lng, err2 := strconv.ParseFloat(rec.Home.Lng, 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return errors.New("Invalid latitude/longitude")
+		}
+	}
+	return nil
+}
+
+// search_bytotal 이 읽는 형식: Start, End 는 YYYYMMDD, Except 는 YYYYMMDD 를 이어 붙인 값
+func checkAvailability(p Petsitter) error {
+	names := []string{"Start", "End"}
+	for i, v := range []string{p.Start, p.End} {
+		if _, err := parseDate(v); err != nil || len(v) != 8 {
+			return errors.New("Invalid " + names[i] + ": " + v)
+		}
+	}
+	if p.Start > p.End {
+		return errors.New("Start is after End")
+	}
+	if len(p.Except)%8 != 0 {
+		return errors.New("Invalid Except: " + p.Except)
+	}
+	for j := 0; j < len(p.Except)/8; j++ {
+		if _, err := parseDate(p.Except[j*8 : (j+1)*8]); err != nil {
+			return errors.New("Invalid Except: " + p.Except)
+		}
+	}
+	return nil
+}
+
+// 프로필, 기본 집, 집 ID 목록, 위치 인덱스, _CCstr 를 저장하고 ID 를 반환
+func writeRegistration(stub shim.ChaincodeStubInterface, rec *Registration) (string, error) {
+	now, err := txTime(stub)
+	if err != nil {
+		return "", err
+	}
+	key, err := piiKey(stub)
+	if err != nil {
+		return "", err
+	}
+	id := registerUser(stub, rec.Email)
+	petsitter := rec.Petsitter
+	petsitter.SaveTime = now.String()
+	petsitter.Status = "active"
+	petsitter.StatusNote = ""
+	petsitter.Rating = "0"
+	petsitter.ReviewNum = "0"
+	petsitter.ReviewSum = "0"
+	petsitter.DocType = ""
+	petsitter.SchemaVersion = ""
+	petsitter.Revision = ""
+	if err := encryptFields(stub, key, id, petsitterPII(&petsitter)); err != nil {
+		return "", err
+	}
+	jsonAsBytes, _ := marshalDoc(&petsitter)
+	stub.PutState(id, jsonAsBytes)
+	CCstr = CCstr + id + "/"
+	stub.PutState("_CCstr", []byte(CCstr))
+
+	if rec.Home == nil {
+		return id, nil
+	}
+	homeAsset := *rec.Home
+	homeAsset.ID = defaultHome
+	homeAsset.SaveTime = now.String()
+	homeAsset.DocType = ""
+	homeAsset.SchemaVersion = ""
+	homeAsset.Revision = ""
+	if err := putHomeAddress(stub, id, defaultHome, &homeAsset, nil); err != nil {
+		return "", err
+	}
+	jsonAsBytes, _ = marshalDoc(&homeAsset)
+	stub.PutState(homeKey(id, defaultHome), jsonAsBytes)
+	addHomeIndex(stub, id, defaultHome)
+	addGeoIndex(stub, id, defaultHome, homeAsset)
+	return id, nil
+}
+
+// JSON 레코드 (bulk_import 와 같은 형식, 집 필수)
+// 프로필과 집을 한 트랜잭션에서 저장하므로 어느 한쪽만 저장되는 일이 없다.
+func (t *PS) register_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Register >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter REGISTER] Incorrect number of arguments. Expecting 1")
+	}
+	rec := Registration{}
+	if err := json.Unmarshal([]byte(args[0]), &rec); err != nil {
+		return nil, errors.New("[Petsitter REGISTER] Invalid JSON: " + err.Error())
+	}
+	if rec.Home == nil {
+		return nil, errors.New("[Petsitter REGISTER] Home is required")
+	}
+	if err := validateRegistration(stub, &rec); err != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Register >>>>")
+		fmt.Println("                             Invalid record")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter REGISTER] " + err.Error())
+	}
+	id, err := writeRegistration(stub, &rec)
+	if err != nil {
+		return nil, errors.New("[Petsitter REGISTER] " + err.Error())
+	}
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                 <<<< Petsitter Register chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return []byte(id), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func registration(email, start, end, except string) string {
+	rec := Registration{Email: email}
+	rec.Petsitter = Petsitter{Nickname: "nick", CostL: "30000", Start: start, End: end, Except: except, TotalNum: "3"}
+	rec.Petsitter.Revision = "99"
+	rec.Petsitter.DocType = "review"
+	rec.Home = &HomeAsset{State: "서울", City: "강남", Street: "테헤란로 1", Code: "06234", Revision: "99", SchemaVersion: "1"}
+	jsonAsBytes, _ := json.Marshal(rec)
+	return string(jsonAsBytes)
+}
+
+func TestRegisterPetsitterDates(t *testing.T) {
+	s := newTestStub(t)
+	s.as("ps@example.com")
+	for _, v := range [][3]string{
+		{"2024-01-01", "20241231", ""},
+		{"20240101", "20241331", ""},
+		{"20241231", "20240101", ""},
+		{"20240101", "20241231", "2024050"},
+		{"20240101", "20241231", "20240230"},
+	} {
+		_, err := s.invoke("register_petsitter", registration("ps@example.com", v[0], v[1], v[2]))
+		expectErr(t, err, "register with dates "+v[0]+" "+v[1]+" "+v[2])
+	}
+	if existState(s, userID(s, "ps@example.com")) {
+		t.Fatal("invalid registration was stored")
+	}
+}
+
+func TestRegisterPetsitterResetsRevision(t *testing.T) {
+	s := newTestStub(t)
+	s.as("ps@example.com")
+	id := string(s.mustInvoke(t, "register_petsitter", registration("ps@example.com", "20240101", "20241231", "2024050120240502")))
+
+	petsitter := Petsitter{}
+	valAsbytes, _ := s.GetState(id)
+	json.Unmarshal(valAsbytes, &petsitter)
+	if petsitter.Revision != "1" || petsitter.DocType != docPetsitter {
+		t.Fatalf("petsitter = %+v", petsitter)
+	}
+	homeAsset := HomeAsset{}
+	valAsbytes, _ = s.GetState(homeKey(id, defaultHome))
+	json.Unmarshal(valAsbytes, &homeAsset)
+	if homeAsset.Revision != "1" || homeAsset.SchemaVersion != schemaVersion {
+		t.Fatalf("home = %+v", homeAsset)
+	}
+}
+
+func TestBulkImportAdminOnly(t *testing.T) {
+	s := newTestStub(t)
+	s.as("ps@example.com")
+	_, err := s.invoke("bulk_import", "["+registration("ps@example.com", "20240101", "20241231", "")+"]")
+	expectErr(t, err, "bulk_import by a non-admin")
+	if existState(s, userID(s, "ps@example.com")) {
+		t.Fatal("bulk_import by a non-admin was stored")
+	}
+}
+
+func TestBulkImportAllOrNothing(t *testing.T) {
+	s := newTestStub(t)
+	s.admin()
+	batch := "[" + registration("a@example.com", "20240101", "20241231", "") + "," +
+		registration("b@example.com", "20241231", "20240101", "") + "]"
+	_, err := s.invoke("bulk_import", batch)
+	expectErr(t, err, "bulk_import with an invalid record")
+	report := ImportReport{}
+	if json.Unmarshal([]byte(err.Error()), &report) != nil {
+		t.Fatalf("error is not a report: %v", err)
+	}
+	if report.Invalid != "1" || report.Results[0].Status != "ok" || report.Results[1].Status != "invalid" {
+		t.Fatalf("report = %+v", report)
+	}
+	if existState(s, userID(s, "a@example.com")) {
+		t.Fatal("valid record of a failed batch was stored")
+	}
+
+	batch = "[" + registration("a@example.com", "20240101", "20241231", "") + "," +
+		registration("b@example.com", "20240101", "20241231", "") + "]"
+	var results []ImportResult
+	json.Unmarshal(s.mustInvoke(t, "bulk_import", batch), &results)
+	if len(results) != 2 || results[1].ID != userID(s, "b@example.com") {
+		t.Fatalf("results = %+v", results)
+	}
+}
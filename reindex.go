@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+type Reindex struct { // reindex result
+	Checked       string // Number of petsitters and consumer indexes checked in this batch
+	Discrepancies []Orphan
+	Next          string // Start key of the next batch ("" if done)
+}
+
+// 시작 키, 최대 처리 건수, 모드(report 또는 repair) (관리자)
+// 주 레코드에서 _CCstr, User ID#t, User ID#r, User ID#homes, CSID#c, 위치 인덱스를 다시 만든다.
+// 결과의 Next 를 다음 호출의 시작 키로 사용하여 여러 트랜잭션에 나누어 처리한다.
+func (t *PS) reindex(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                              <<<< Reindex >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[REINDEX] Incorrect number of arguments. Expecting 3")
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                              <<<< Reindex >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[REINDEX] Caller is not an admin")
+	}
+	limit, err := strconv.Atoi(args[1])
+	if err != nil || limit <= 0 {
+		return nil, errors.New("[REINDEX] Invalid limit: " + args[1])
+	}
+	if args[2] != "report" && args[2] != "repair" {
+		return nil, errors.New("[REINDEX] Invalid mode: " + args[2])
+	}
+	repair := args[2] == "repair"
+
+	ret := Reindex{}
+	ret.Discrepancies = []Orphan{}
+	var cstr string
+	confc, _ := stub.GetState("_CCstr")
+	if confc != nil {
+		cstr = string(confc)
+	}
+	listed := splitIndex(cstr)
+
+	// 전체 인덱스의 남은 항목은 첫 번째 배치에서 정리
+	if args[0] == "" {
+		var kept []string
+		seen := map[string]bool{}
+		for _, id := range listed {
+			conf, _ := stub.GetState(id)
+			if seen[id] {
+				ret.Discrepancies = append(ret.Discrepancies, Orphan{"_CCstr", "Duplicate entry " + id})
+			} else if conf == nil || petsitterDeleted(conf) {
+				ret.Discrepancies = append(ret.Discrepancies, Orphan{"_CCstr", "Not exist Petsitter " + id})
+			} else {
+				kept = append(kept, id)
+			}
+			seen[id] = true
+		}
+		listed = kept
+		stale, err := staleGeoKeys(stub)
+		if err != nil {
+			return nil, errors.New("[REINDEX] " + err.Error())
+		}
+		for _, key := range stale {
+			ret.Discrepancies = append(ret.Discrepancies, Orphan{key, "Stale geo index entry"})
+			if repair {
+				stub.DelState(key)
+			}
+		}
+	}
+
+	ids, next, err := petsitterBatch(stub, args[0], limit)
+	if err != nil {
+		return nil, errors.New("[REINDEX] " + err.Error())
+	}
+	for _, id := range ids {
+		if strings.HasSuffix(id, "#c") {
+			ret.Discrepancies = append(ret.Discrepancies, reindexConsumer(stub, id, repair)...)
+			continue
+		}
+		found, err := reindexPetsitter(stub, id, repair)
+		if err != nil {
+			return nil, errors.New("[REINDEX] " + err.Error())
+		}
+		ret.Discrepancies = append(ret.Discrepancies, found...)
+
+		conf, _ := stub.GetState(id)
+		want := !petsitterDeleted(conf)
+		has := false
+		for _, v := range listed {
+			if v == id {
+				has = true
+			}
+		}
+		if want && !has {
+			ret.Discrepancies = append(ret.Discrepancies, Orphan{"_CCstr", "Missing entry " + id})
+			listed = append(listed, id)
+		} else if !want && has {
+			ret.Discrepancies = append(ret.Discrepancies, Orphan{"_CCstr", "Deleted Petsitter " + id})
+			var kept []string
+			for _, v := range listed {
+				if v != id {
+					kept = append(kept, v)
+				}
+			}
+			listed = kept
+		}
+	}
+	if repair {
+		CCstr = "/"
+		if len(listed) > 0 {
+			CCstr = "/" + strings.Join(listed, "/") + "/"
+		}
+		stub.PutState("_CCstr", []byte(CCstr))
+	}
+	ret.Checked = strconv.Itoa(len(ids))
+	ret.Next = next
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                     <<<< Reindex chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return json.Marshal(ret)
+}
+
+// 시작 키부터 펫시터 주 레코드 키와 고객별 거래 목록 키를 최대 limit 개
+func petsitterBatch(stub shim.ChaincodeStubInterface, start string, limit int) ([]string, string, error) {
+	if start == "" {
+		start = firstKey
+	}
+	iter, err := stub.RangeQueryState(start, lastKey)
+	if err != nil {
+		return nil, "", err
+	}
+	defer iter.Close()
+	var ids []string
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			return nil, "", err
+		}
+		parts := strings.Split(key, "#")
+		if docTypeOf(key) != docPetsitter && !(len(parts) == 2 && parts[1] == "c" && !strings.HasPrefix(key, "_")) {
+			continue
+		}
+		if len(ids) == limit {
+			return ids, key, nil
+		}
+		ids = append(ids, key)
+	}
+	return ids, "", nil
+}
+
+// 펫시터 한 명의 거래, 리뷰, 집 목록, 위치 인덱스를 확인
+func reindexPetsitter(stub shim.ChaincodeStubInterface, id string, repair bool) ([]Orphan, error) {
+	iter, err := stub.RangeQueryState(id+"#", id+"#"+lastKey)
+	if err != nil {
+		return nil, err
+	}
+	var trades, reviews, hids []string
+	consumers := map[string][]string{}
+	homes := map[string]HomeAsset{}
+	for iter.HasNext() {
+		key, val, err := iter.Next()
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		switch docTypeOf(key) {
+		case docTrade:
+			trades = append(trades, key)
+			csid := strings.Split(key, "#")[1]
+			consumers[csid] = append(consumers[csid], key)
+		case docReview:
+			review := Review{}
+			json.Unmarshal(val, &review)
+			if review.Revealed == "true" {
+				reviews = append(reviews, key)
+			}
+		case docHome:
+			homeAsset := HomeAsset{}
+			json.Unmarshal(val, &homeAsset)
+			hid := defaultHome
+			if parts := strings.Split(key, "#"); len(parts) == 3 {
+				hid = parts[2]
+			}
+			hids = append(hids, hid)
+			homes[hid] = homeAsset
+		}
+	}
+	iter.Close()
+
+	var found []Orphan
+	for _, v := range []struct {
+		key     string
+		entries []string
+	}{{id + "#t", trades}, {id + "#r", reviews}} {
+		var idx string
+		conf, _ := stub.GetState(v.key)
+		json.Unmarshal(conf, &idx)
+		if sameEntries(splitIndex(idx), v.entries) {
+			continue
+		}
+		found = append(found, Orphan{v.key, "Index differs from records (" + strconv.Itoa(len(v.entries)) + " records)"})
+		if repair {
+			if len(v.entries) == 0 {
+				stub.DelState(v.key)
+			} else {
+				ibyte, _ := json.Marshal("/" + strings.Join(v.entries, "/") + "/")
+				stub.PutState(v.key, ibyte)
+			}
+		}
+	}
+	if !sameEntries(homeIDs(stub, id), hids) {
+		found = append(found, Orphan{id + "#homes", "Index differs from records (" + strconv.Itoa(len(hids)) + " records)"})
+		if repair {
+			putHomeIndex(stub, id, hids)
+		}
+	}
+	// 고객별 목록의 빠진 거래 (남은 항목은 reindexConsumer 에서 확인)
+	var csids []string
+	for csid := range consumers {
+		csids = append(csids, csid)
+	}
+	sort.Strings(csids)
+	for _, csid := range csids {
+		keys := consumers[csid]
+		var idx string
+		conf, _ := stub.GetState(consumerIndexKey(csid))
+		json.Unmarshal(conf, &idx)
+		listed := map[string]bool{}
+		for _, v := range splitIndex(idx) {
+			listed[v] = true
+		}
+		for _, key := range keys {
+			if listed[key] {
+				continue
+			}
+			found = append(found, Orphan{consumerIndexKey(csid), "Missing entry " + key})
+			if repair {
+				appendIndex(stub, consumerIndexKey(csid), key)
+			}
+		}
+	}
+	for _, hid := range hids {
+		key := geoKey(id, hid, homes[hid])
+		if key != "" && !existState(stub, key) {
+			found = append(found, Orphan{key, "Missing geo index entry"})
+			if repair {
+				addGeoIndex(stub, id, hid, homes[hid])
+			}
+		}
+	}
+	return found, nil
+}
+
+// 고객별 거래 목록에서 거래가 없거나 고객이 다른 항목을 뺀다
+func reindexConsumer(stub shim.ChaincodeStubInterface, key string, repair bool) []Orphan {
+	csid := strings.TrimSuffix(key, "#c")
+	var idx string
+	conf, _ := stub.GetState(key)
+	json.Unmarshal(conf, &idx)
+	var found []Orphan
+	var kept []string
+	for _, v := range splitIndex(idx) {
+		parts := strings.Split(v, "#")
+		if len(parts) != 3 || parts[1] != csid || !existState(stub, v) {
+			found = append(found, Orphan{key, "Dangling index entry " + v})
+			continue
+		}
+		kept = append(kept, v)
+	}
+	if repair && len(found) > 0 {
+		if len(kept) == 0 {
+			stub.DelState(key)
+		} else {
+			ibyte, _ := json.Marshal("/" + strings.Join(kept, "/") + "/")
+			stub.PutState(key, ibyte)
+		}
+	}
+	return found
+}
+
+// 집이 없거나 좌표가 바뀐 위치 인덱스
+func staleGeoKeys(stub shim.ChaincodeStubInterface) ([]string, error) {
+	iter, err := stub.RangeQueryState("_geo#", "_geo#"+lastKey)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	iter.Close()
+
+	var stale []string
+	for _, key := range keys {
+		parts := strings.Split(key, "#")
+		if len(parts) != 4 {
+			stale = append(stale, key)
+			continue
+		}
+		conf, _ := stub.GetState(homeKey(parts[2], parts[3]))
+		homeAsset := HomeAsset{}
+		json.Unmarshal(conf, &homeAsset)
+		if conf == nil || geoKey(parts[2], parts[3], homeAsset) != key {
+			stale = append(stale, key)
+		}
+	}
+	return stale, nil
+}
+
+// 순서와 관계없이 같은 항목인지
+func sameEntries(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]string{}, a...)
+	y := append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func reindexRun(t *testing.T, s *testStub, start, limit, mode string) Reindex {
+	t.Helper()
+	s.admin()
+	ret := Reindex{}
+	json.Unmarshal(s.mustInvoke(t, "reindex", start, limit, mode), &ret)
+	return ret
+}
+
+// 펫시터 두 명, 거래, 위치 인덱스를 만들고 인덱스를 망가뜨린다
+func corruptedIndexes(t *testing.T) (*testStub, string, string) {
+	s := newTestStub(t)
+	a := s.petsitter(t, "a@example.com")
+	b := s.petsitter(t, "b@example.com")
+	s.as("a@example.com")
+	s.mustInvoke(t, "save_named_home", namedHomeArgs("a@example.com", "cottage", "cottage")...)
+	s.mustInvoke(t, "set_home_location", "a@example.com", "cottage", "37.5", "127.03")
+	s.trade(t, a, "cs@example.com", "20240620", "20240622")
+	s.trade(t, b, "cs@example.com", "20240620", "20240622")
+
+	s.begin()
+	s.DelState(a + "#t")
+	s.DelState(geoKey(a, "cottage", readHome(s, a, "cottage")))
+	s.PutState("_geo#wydm9#"+b+"#none", []byte("x"))
+	CCstr = "/" + a + "/ghost/"
+	s.PutState("_CCstr", []byte(CCstr))
+	s.end()
+	return s, a, b
+}
+
+func TestReindexReportAndRepair(t *testing.T) {
+	s, a, b := corruptedIndexes(t)
+
+	s.as("a@example.com")
+	_, err := s.invoke("reindex", "", "100", "report")
+	expectErr(t, err, "reindex by a non-admin")
+
+	report := reindexRun(t, s, "", "100", "report")
+	var found []string
+	for _, v := range report.Discrepancies {
+		found = append(found, v.Key+": "+v.Reason)
+	}
+	all := strings.Join(found, "\n")
+	for _, want := range []string{"_CCstr: Not exist Petsitter ghost", "_CCstr: Missing entry " + b, a + "#t: Index differs", "Missing geo index entry", "Stale geo index entry"} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("report lacks %q:\n%s", want, all)
+		}
+	}
+	// report 모드는 바꾸지 않는다
+	if existState(s, a+"#t") || CCstr != "/"+a+"/ghost/" {
+		t.Fatal("report mode changed the state")
+	}
+
+	reindexRun(t, s, "", "100", "repair")
+	if again := reindexRun(t, s, "", "100", "report"); len(again.Discrepancies) != 0 {
+		t.Fatalf("after repair = %+v", again.Discrepancies)
+	}
+	var idx string
+	conf, _ := s.GetState(a + "#t")
+	json.Unmarshal(conf, &idx)
+	if len(splitIndex(idx)) != 1 || !strings.Contains(CCstr, b) || strings.Contains(CCstr, "ghost") {
+		t.Fatalf("index = %s, CCstr = %s", idx, CCstr)
+	}
+}
+
+func TestReindexBatches(t *testing.T) {
+	s, _, _ := corruptedIndexes(t)
+	full := reindexRun(t, s, "", "100", "report")
+
+	start := ""
+	checked := 0
+	var found []Orphan
+	for i := 0; ; i++ {
+		if i > 10 {
+			t.Fatal("reindex does not finish")
+		}
+		ret := reindexRun(t, s, start, "1", "repair")
+		if ret.Checked != "1" && ret.Next != "" {
+			t.Fatalf("batch %d checked %s", i, ret.Checked)
+		}
+		n := 0
+		json.Unmarshal([]byte(ret.Checked), &n)
+		checked += n
+		found = append(found, ret.Discrepancies...)
+		if ret.Next == "" {
+			break
+		}
+		if ret.Next <= start {
+			t.Fatalf("cursor did not advance: %s -> %s", start, ret.Next)
+		}
+		start = ret.Next
+	}
+	// 펫시터 2명과 고객 목록 1개
+	if checked != 3 || len(found) != len(full.Discrepancies) {
+		t.Fatalf("checked %d, found %+v, want %+v", checked, found, full.Discrepancies)
+	}
+	if again := reindexRun(t, s, "", "100", "report"); len(again.Discrepancies) != 0 {
+		t.Fatalf("after batched repair = %+v", again.Discrepancies)
+	}
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+type Review struct { // Review of a petsitter (KEY: PSID#CSID#거래 ID#review)
+	PSID          string // Petsitter ID
+	CSID          string // Consumer ID (reviewer)
+	TC            string // Transaction complete time
+	Score         string // 1 ~ 5
+	Text          string
+	SaveTime      string
+	Revealed      string // "false" until both sides reviewed or the deadline passed
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
+}
+
+type ConsumerReview struct { // Review of a consumer and pet (KEY: PSID#CSID#거래 ID#creview)
+	PSID          string // Petsitter ID (reviewer)
+	CSID          string // Consumer ID
+	TC            string // Transaction complete time
+	Score         string // Consumer 1 ~ 5
+	PetScore      string // Pet 1 ~ 5
+	Text          string
+	SaveTime      string
+	Revealed      string
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
+}
+
+type ConsumerRating struct { // Aggregate rating of a consumer (KEY: CSID#rating)
+	Rating        string // Average consumer score
+	PetRating     string // Average pet score
+	ReviewNum     string
+	ReviewSum     string
+	PetReviewSum  string
+	DocType       string // Record type
+	SchemaVersion string
+	Revision      string // Incremented on every write
+}
+
+type ReviewPage struct { // list_reviews result
+	Total   string
+	Next    string // Offset of the next page ("" if last)
+	Reviews []Review
+}
+
+// PSID, CSID, 거래 ID, 점수, 내용
+func (t *PS) submit_review(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 5 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Review Insert >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 5")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review INSSERT] Incorrect number of arguments. Expecting 5")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	valAsbytes, _ := stub.GetState(key)
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Review Insert >>>>")
+		fmt.Println("                           Not exist transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review INSSERT] Not exist transaction")
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	if userID(stub, callerAttr(stub, "email")) != tradeRec.CSID {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Review Insert >>>>")
+		fmt.Println("                        Caller is not the consumer")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review INSSERT] Caller is not the consumer")
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Review INSSERT] " + err.Error())
+	}
+	if !tradeCompleted(tradeRec, now) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Review Insert >>>>")
+		fmt.Println("                          Not completed transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review INSSERT] Not completed transaction")
+	}
+	if reviewDeadlinePassed(tradeRec, now) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Review Insert >>>>")
+		fmt.Println("                          Review deadline has passed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review INSSERT] Review deadline has passed")
+	}
+	conf, _ := stub.GetState(key + "#review")
+	if conf != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Review Insert >>>>")
+		fmt.Println("                            Already exist Review")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review INSSERT] Already exist Review")
+	}
+	score, err := strconv.Atoi(args[3])
+	if err != nil || score < 1 || score > 5 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Review Insert >>>>")
+		fmt.Println("                        Score must be between 1 and 5")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review INSSERT] Score must be between 1 and 5")
+	}
+	confUser, _ := stub.GetState(tradeRec.PSID)
+	if confUser == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< Review Insert >>>>")
+		fmt.Println("                               Not exist Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review INSSERT] Not exist Petsitter")
+	}
+
+	review := Review{tradeRec.PSID, tradeRec.CSID, tradeRec.TC, args[3], args[4], now.String(), "false", docReview, schemaVersion, ""}
+	jsonAsBytes, _ := marshalDoc(&review)
+	stub.PutState(key+"#review", jsonAsBytes)
+	revealReviews(stub, key, tradeRec, now)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Review Insert chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// PSID, offset, limit
+func (t *PS) list_reviews(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Review List >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review QUERY] Incorrect number of arguments. Expecting 3")
+	}
+	offset, err1 := strconv.Atoi(args[1])
+	limit, err2 := strconv.Atoi(args[2])
+	if err1 != nil || err2 != nil || offset < 0 || limit < 1 {
+		return nil, errors.New("[Review QUERY] Invalid offset or limit")
+	}
+	var rstr string
+	confr, _ := stub.GetState(args[0] + "#r")
+	json.Unmarshal(confr, &rstr)
+	keys := splitIndex(rstr)
+
+	page := ReviewPage{}
+	page.Total = strconv.Itoa(len(keys))
+	page.Reviews = []Review{}
+	for i := offset; i < len(keys) && i < offset+limit; i++ {
+		valAsbytes, _ := stub.GetState(keys[i])
+		if valAsbytes == nil {
+			continue
+		}
+		review := Review{}
+		json.Unmarshal(valAsbytes, &review)
+		page.Reviews = append(page.Reviews, review)
+	}
+	if offset+limit < len(keys) {
+		page.Next = strconv.Itoa(offset + limit)
+	}
+	return json.Marshal(page)
+}
+
+// PSID, CSID, 거래 ID, 고객 점수, 반려동물 점수, 내용
+func (t *PS) submit_consumer_review(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 6 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Consumer Review Insert >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 6")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer Review INSSERT] Incorrect number of arguments. Expecting 6")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	valAsbytes, _ := stub.GetState(key)
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Consumer Review Insert >>>>")
+		fmt.Println("                           Not exist transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer Review INSSERT] Not exist transaction")
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	if userID(stub, callerAttr(stub, "email")) != tradeRec.PSID {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Consumer Review Insert >>>>")
+		fmt.Println("                        Caller is not the petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer Review INSSERT] Caller is not the petsitter")
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Consumer Review INSSERT] " + err.Error())
+	}
+	if !tradeCompleted(tradeRec, now) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Consumer Review Insert >>>>")
+		fmt.Println("                          Not completed transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer Review INSSERT] Not completed transaction")
+	}
+	if reviewDeadlinePassed(tradeRec, now) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Consumer Review Insert >>>>")
+		fmt.Println("                          Review deadline has passed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer Review INSSERT] Review deadline has passed")
+	}
+	conf, _ := stub.GetState(key + "#creview")
+	if conf != nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Consumer Review Insert >>>>")
+		fmt.Println("                            Already exist Review")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer Review INSSERT] Already exist Review")
+	}
+	score, err1 := strconv.Atoi(args[3])
+	petScore, err2 := strconv.Atoi(args[4])
+	if err1 != nil || err2 != nil || score < 1 || score > 5 || petScore < 1 || petScore > 5 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Consumer Review Insert >>>>")
+		fmt.Println("                        Score must be between 1 and 5")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer Review INSSERT] Score must be between 1 and 5")
+	}
+
+	review := ConsumerReview{tradeRec.PSID, tradeRec.CSID, tradeRec.TC, args[3], args[4], args[5], now.String(), "false", docConsumerReview, schemaVersion, ""}
+	jsonAsBytes, _ := marshalDoc(&review)
+	stub.PutState(key+"#creview", jsonAsBytes)
+	revealReviews(stub, key, tradeRec, now)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("              <<<< Consumer Review Insert chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// PSID, CSID, 거래 ID (리뷰 마감 이후 한쪽만 작성된 리뷰 공개)
+func (t *PS) reveal_reviews(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Review Reveal >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review REVEAL] Incorrect number of arguments. Expecting 3")
+	}
+	key := args[0] + "#" + args[1] + "#" + args[2]
+	valAsbytes, _ := stub.GetState(key)
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Review Reveal >>>>")
+		fmt.Println("                           Not exist transaction")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review REVEAL] Not exist transaction")
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Review REVEAL] " + err.Error())
+	}
+	if !revealReviews(stub, key, tradeRec, now) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< Review Reveal >>>>")
+		fmt.Println("                      Review deadline has not passed")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Review REVEAL] Review deadline has not passed")
+	}
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< Review Reveal chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+func (t *PS) read_consumer_rating(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Consumer Rating Read >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer Rating QUERY] Incorrect number of arguments. Expecting 1")
+	}
+	valAsbytes, _ := stub.GetState(args[0] + "#rating")
+	if valAsbytes == nil {
+		rating := ConsumerRating{"0", "0", "0", "0", "0", docConsumerRating, schemaVersion, ""}
+		return json.Marshal(rating)
+	}
+	return valAsbytes, nil
+}
+
+// 양쪽 리뷰가 모두 작성되었거나 마감이 지나면 공개하고 평점에 반영
+func revealReviews(stub shim.ChaincodeStubInterface, key string, tradeRec TradeRec, now time.Time) bool {
+	review := Review{}
+	creview := ConsumerReview{}
+	rbytes, _ := stub.GetState(key + "#review")
+	cbytes, _ := stub.GetState(key + "#creview")
+	json.Unmarshal(rbytes, &review)
+	json.Unmarshal(cbytes, &creview)
+	if (rbytes == nil || cbytes == nil) && !reviewDeadlinePassed(tradeRec, now) {
+		return false
+	}
+
+	if rbytes != nil && review.Revealed == "false" {
+		review.Revealed = "true"
+		jsonAsBytes, _ := marshalDoc(&review)
+		stub.PutState(key+"#review", jsonAsBytes)
+
+		confUser, _ := stub.GetState(tradeRec.PSID)
+		if confUser != nil {
+			score, _ := strconv.Atoi(review.Score)
+			petsitter := Petsitter{}
+			json.Unmarshal(confUser, &petsitter)
+			addRating(&petsitter.ReviewNum, &petsitter.ReviewSum, &petsitter.Rating, score)
+			jsonAsBytes, _ = marshalDoc(&petsitter)
+			stub.PutState(tradeRec.PSID, jsonAsBytes)
+		}
+
+		appendIndex(stub, tradeRec.PSID+"#r", key+"#review")
+	}
+
+	if cbytes != nil && creview.Revealed == "false" {
+		creview.Revealed = "true"
+		jsonAsBytes, _ := marshalDoc(&creview)
+		stub.PutState(key+"#creview", jsonAsBytes)
+
+		rating := ConsumerRating{}
+		confRating, _ := stub.GetState(tradeRec.CSID + "#rating")
+		json.Unmarshal(confRating, &rating)
+		score, _ := strconv.Atoi(creview.Score)
+		petScore, _ := strconv.Atoi(creview.PetScore)
+		num := rating.ReviewNum
+		addRating(&rating.ReviewNum, &rating.ReviewSum, &rating.Rating, score)
+		addRating(&num, &rating.PetReviewSum, &rating.PetRating, petScore)
+		jsonAsBytes, _ = marshalDoc(&rating)
+		stub.PutState(tradeRec.CSID+"#rating", jsonAsBytes)
+	}
+	return true
+}
+
+// 리뷰 작성 기간 (이용 종료 후)
+const reviewWindow = 14 * 24 * time.Hour
+
+// 이용 종료(TE 다음 날 0시) 후 14일
+func reviewDeadlinePassed(tradeRec TradeRec, now time.Time) bool {
+	end, err := parseDate(tradeRec.TE)
+	if err != nil {
+		return false
+	}
+	return now.After(end.Add(24*time.Hour + reviewWindow))
+}
+
+// 펫시터가 수락(accept_booking)하고 TE 가 지난 거래
+func tradeCompleted(tradeRec TradeRec, now time.Time) bool {
+	return tradeRec.Status == "accepted" && tradeEnded(tradeRec, now)
+}
+
+// TE 다음 날 0시가 지난 거래
+func tradeEnded(tradeRec TradeRec, now time.Time) bool {
+	end, err := parseDate(tradeRec.TE)
+	if err != nil {
+		return false
+	}
+	return now.After(end.Add(24 * time.Hour))
+}
+
+func addRating(num, sum, avg *string, score int) {
+	n, _ := strconv.Atoi(*num)
+	s, _ := strconv.Atoi(*sum)
+	n = n + 1
+	s = s + score
+	*num = strconv.Itoa(n)
+	*sum = strconv.Itoa(s)
+	*avg = strconv.FormatFloat(float64(s)/float64(n), 'f', 2, 64)
+}
+
+// "/a/b/c/" -> [a b c]
+func splitIndex(s string) []string {
+	var ret []string
+	for _, v := range strings.Split(s, "/") {
+		if v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSubmitReviewCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.acceptedTrade(t, "ps@example.com", "cs@example.com", "20240520", "20240525")
+	csid := userID(s, "cs@example.com")
+
+	for _, caller := range []string{"other@example.com", "ps@example.com", ""} {
+		s.as(caller)
+		_, err := s.invoke("submit_review", psid, csid, tid, "5", "good")
+		expectErr(t, err, "review by "+caller)
+	}
+	if existState(s, tradeKey(psid, csid, tid)+"#review") {
+		t.Fatal("review stored for a rejected caller")
+	}
+
+	s.as("cs@example.com")
+	s.mustInvoke(t, "submit_review", psid, csid, tid, "5", "good")
+}
+
+func TestSubmitConsumerReviewCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.acceptedTrade(t, "ps@example.com", "cs@example.com", "20240520", "20240525")
+	csid := userID(s, "cs@example.com")
+
+	for _, caller := range []string{"other@example.com", "cs@example.com"} {
+		s.as(caller)
+		_, err := s.invoke("submit_consumer_review", psid, csid, tid, "1", "1", "bad")
+		expectErr(t, err, "consumer review by "+caller)
+	}
+
+	s.as("ps@example.com")
+	s.mustInvoke(t, "submit_consumer_review", psid, csid, tid, "4", "5", "ok")
+}
+
+func TestRevealDeadline(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.acceptedTrade(t, "ps@example.com", "cs@example.com", "20240520", "20240525")
+	csid := userID(s, "cs@example.com")
+	s.as("cs@example.com")
+	s.mustInvoke(t, "submit_review", psid, csid, tid, "5", "good")
+
+	// TE 다음 날 0시(5/26)부터 14일
+	s.now = time.Date(2024, 6, 9, 0, 0, 0, 0, time.UTC)
+	_, err := s.invoke("reveal_reviews", psid, csid, tid)
+	expectErr(t, err, "reveal before the deadline")
+
+	s.now = s.now.Add(time.Second)
+	s.mustInvoke(t, "reveal_reviews", psid, csid, tid)
+	review := Review{}
+	conf, _ := s.GetState(tradeKey(psid, csid, tid) + "#review")
+	json.Unmarshal(conf, &review)
+	if review.Revealed != "true" {
+		t.Fatal("review not revealed after the deadline")
+	}
+}
+
+func TestReviewNeedsAcceptedTrade(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+
+	// 이미 지난 날짜로는 예약할 수 없다
+	s.as("cs@example.com")
+	_, err := s.invoke("save_tran", psid, "nick", "cs@example.com", "20240520", "20240525", "20240525", "100000", "booked")
+	expectErr(t, err, "backdated booking")
+
+	// 수락되지 않은 거래는 TE 가 지나도 후기를 쓸 수 없다
+	tid := s.trade(t, psid, "cs@example.com", "20240610", "20240612")
+	csid := userID(s, "cs@example.com")
+	s.now = time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	s.as("cs@example.com")
+	_, err = s.invoke("submit_review", psid, csid, tid, "5", "good")
+	expectErr(t, err, "review of an unaccepted trade")
+}
+
+func TestConsumerReviewNeedsConsumerBooking(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+
+	// 펫시터가 고객 이름으로 지난 거래를 만들 수 없다
+	s.as("ps@example.com")
+	for _, dates := range [][2]string{{"20240520", "20240525"}, {"20240610", "20240612"}} {
+		_, err := s.invoke("save_tran", psid, "nick", "cs@example.com", dates[0], dates[1], dates[1], "100000", "booked")
+		expectErr(t, err, "booking in the consumer's name "+dates[0])
+	}
+
+	// 고객이 예약했지만 수락되지 않은 거래로는 고객 후기를 쓸 수 없다
+	tid := s.trade(t, psid, "cs@example.com", "20240610", "20240612")
+	csid := userID(s, "cs@example.com")
+	s.now = time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	s.as("ps@example.com")
+	_, err := s.invoke("submit_consumer_review", psid, csid, tid, "1", "1", "bad")
+	expectErr(t, err, "consumer review of an unaccepted trade")
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 저장 레코드 스키마 버전
+// 버전 필드가 없는 기존 레코드는 1 로 본다. 필드를 추가하면 schemaVersion 을 올리고 migrations 에 변환 함수를 등록한다.
+const schemaVersion = "5"
+
+const (
+	docPetsitter      = "petsitter"
+	docHome           = "home"
+	docAddress        = "address"
+	docTrade          = "trade"
+	docCancelPolicy   = "cancel_policy"
+	docCancellation   = "cancellation"
+	docReview         = "review"
+	docConsumerReview = "consumer_review"
+	docConsumerRating = "consumer_rating"
+	docDispute        = "dispute"
+	docErasure        = "erasure_receipt"
+)
+
+type SchemaMigration struct { // migrate_schema result
+	Scanned  string
+	Migrated string
+	Next     string // Start key of the next batch ("" if done)
+}
+
+type versioned interface {
+	stamp()
+}
+
+func (r *Petsitter) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docPetsitter, schemaVersion, nextRevision(r.Revision)
+}
+func (r *HomeAsset) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docHome, schemaVersion, nextRevision(r.Revision)
+}
+func (r *HomeAddress) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docAddress, schemaVersion, nextRevision(r.Revision)
+}
+func (r *TradeRec) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docTrade, schemaVersion, nextRevision(r.Revision)
+}
+func (r *CancelPolicy) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docCancelPolicy, schemaVersion, nextRevision(r.Revision)
+}
+func (r *CancelRec) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docCancellation, schemaVersion, nextRevision(r.Revision)
+}
+func (r *Review) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docReview, schemaVersion, nextRevision(r.Revision)
+}
+func (r *ConsumerReview) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docConsumerReview, schemaVersion, nextRevision(r.Revision)
+}
+func (r *ConsumerRating) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docConsumerRating, schemaVersion, nextRevision(r.Revision)
+}
+func (r *Dispute) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docDispute, schemaVersion, nextRevision(r.Revision)
+}
+func (r *ErasureReceipt) stamp() {
+	r.DocType, r.SchemaVersion, r.Revision = docErasure, schemaVersion, nextRevision(r.Revision)
+}
+
+// 저장용 직렬화 (DocType, SchemaVersion 기록, Revision 증가)
+func marshalDoc(v versioned) ([]byte, error) {
+	v.stamp()
+	return json.Marshal(v)
+}
+
+// Revision 이 없는 기존 레코드는 0 으로 본다
+func nextRevision(rev string) string {
+	n, _ := strconv.Atoi(rev)
+	return strconv.Itoa(n + 1)
+}
+
+// 기대 revision 이 ""이면 확인하지 않는다
+func checkRevision(current, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	if current == "" {
+		current = "0"
+	}
+	if current != expected {
+		return errors.New("Conflict: expected revision " + expected + ", current " + current)
+	}
+	return nil
+}
+
+// migrations[DocType][i] 는 버전 i+1 레코드를 i+2 로 바꾼다 (nil 이면 변경 없음).
+// 레코드는 map 으로 다루므로 구조체에 없는 필드도 그대로 남는다. 함께 저장할 다른 레코드는 puts 에 넣는다.
+type migration func(key string, doc map[string]interface{}, puts map[string][]byte)
+
+var migrations = map[string][]migration{
+	docPetsitter: {migratePetsitterV2},
+	docHome:      {migrateHomeV2, nil, nil, migrateHomeV5},
+	docTrade:     {migrateTradeV2, migrateTradeV3},
+}
+
+// 상태, 평점 필드가 추가되기 전 레코드
+func migratePetsitterV2(key string, doc map[string]interface{}, puts map[string][]byte) {
+	if docString(doc, "Status") == "" {
+		doc["Status"] = "active"
+	}
+	for _, name := range []string{"Rating", "ReviewNum", "ReviewSum"} {
+		if docString(doc, name) == "" {
+			doc[name] = "0"
+		}
+	}
+}
+
+// 집 ID 가 추가되기 전 레코드 (User ID#home)
+func migrateHomeV2(key string, doc map[string]interface{}, puts map[string][]byte) {
+	if docString(doc, "ID") == "" {
+		parts := strings.Split(key, "#")
+		if len(parts) == 3 {
+			doc["ID"] = parts[2]
+		} else {
+			doc["ID"] = defaultHome
+		}
+	}
+}
+
+// 상세 주소가 분리되기 전 레코드 (Street, Adt, Code 를 User ID#addr#집 ID 로 옮긴다)
+func migrateHomeV5(key string, doc map[string]interface{}, puts map[string][]byte) {
+	addr := HomeAddress{Street: docString(doc, "Street"), Adt: docString(doc, "Adt"), Code: docString(doc, "Code")}
+	if addr.Street == "" && addr.Adt == "" && addr.Code == "" {
+		return
+	}
+	parts := strings.Split(key, "#")
+	hid := defaultHome
+	if len(parts) == 3 {
+		hid = parts[2]
+	}
+	puts[addrKey(parts[0], hid)], _ = marshalDoc(&addr)
+	doc["Street"], doc["Adt"], doc["Code"] = "", "", ""
+}
+
+// 상태가 추가되기 전 레코드는 예약 상태
+func migrateTradeV2(key string, doc map[string]interface{}, puts map[string][]byte) {
+	if docString(doc, "Status") == "" {
+		doc["Status"] = "booked"
+	}
+}
+
+// 거래 ID 가 추가되기 전 레코드는 키의 TC 를 거래 ID 로 사용
+func migrateTradeV3(key string, doc map[string]interface{}, puts map[string][]byte) {
+	if docString(doc, "TradeID") == "" {
+		doc["TradeID"] = strings.Split(key, "#")[2]
+	}
+}
+
+func docString(doc map[string]interface{}, name string) string {
+	s, _ := doc[name].(string)
+	return s
+}
+
+// 키 형태로 레코드 종류를 판별 (인덱스, 설정 값은 "")
+func docTypeOf(key string) string {
+	parts := strings.Split(key, "#")
+	if strings.HasPrefix(key, "_") {
+		if parts[0] == "_erasure" {
+			return docErasure
+		}
+		return ""
+	}
+	switch len(parts) {
+	case 1:
+		return docPetsitter
+	case 2:
+		switch parts[1] {
+		case "home":
+			return docHome
+		case "policy":
+			return docCancelPolicy
+		case "rating":
+			return docConsumerRating
+		}
+	case 3:
+		switch parts[1] {
+		case "home":
+			return docHome
+		case "addr":
+			return docAddress
+		}
+		return docTrade
+	case 4:
+		switch parts[3] {
+		case "cancel":
+			return docCancellation
+		case "review":
+			return docReview
+		case "creview":
+			return docConsumerReview
+		case "dispute":
+			return docDispute
+		}
+	}
+	return ""
+}
+
+// 시작 키, 최대 처리 건수 (관리자)
+// 한 트랜잭션에서 처리할 양을 나누어 여러 번 호출한다. 결과의 Next 를 다음 호출의 시작 키로 사용한다.
+func (t *PS) migrate_schema(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 2 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Schema Migrate >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 2")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Schema MIGRATE] Incorrect number of arguments. Expecting 2")
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Schema Migrate >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Schema MIGRATE] Caller is not an admin")
+	}
+	limit, err := strconv.Atoi(args[1])
+	if err != nil || limit <= 0 {
+		return nil, errors.New("[Schema MIGRATE] Invalid limit: " + args[1])
+	}
+	ret, err := migrateSchema(stub, args[0], limit)
+	if err != nil {
+		return nil, errors.New("[Schema MIGRATE] " + err.Error())
+	}
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                 <<<< Schema Migrate chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return json.Marshal(ret)
+}
+
+func migrateSchema(stub shim.ChaincodeStubInterface, start string, limit int) (SchemaMigration, error) {
+	ret := SchemaMigration{}
+	if start == "" {
+		start = firstKey
+	}
+	iter, err := stub.RangeQueryState(start, lastKey)
+	if err != nil {
+		return ret, err
+	}
+	puts := map[string][]byte{}
+	scanned, migrated := 0, 0
+	for iter.HasNext() {
+		key, val, err := iter.Next()
+		if err != nil {
+			iter.Close()
+			return ret, err
+		}
+		if scanned == limit {
+			ret.Next = key
+			break
+		}
+		scanned++
+		docType := docTypeOf(key)
+		if docType == "" {
+			continue
+		}
+		doc := map[string]interface{}{}
+		if json.Unmarshal(val, &doc) != nil {
+			continue
+		}
+		if docString(doc, "SchemaVersion") == schemaVersion {
+			continue
+		}
+		if err := upgradeDoc(key, docType, doc, puts); err != nil {
+			iter.Close()
+			return ret, errors.New(key + ": " + err.Error())
+		}
+		puts[key], _ = json.Marshal(doc)
+		migrated++
+	}
+	iter.Close()
+	for key, val := range puts {
+		stub.PutState(key, val)
+	}
+	ret.Scanned = strconv.Itoa(scanned)
+	ret.Migrated = strconv.Itoa(migrated)
+	return ret, nil
+}
+
+func upgradeDoc(key, docType string, doc map[string]interface{}, puts map[string][]byte) error {
+	version := 1
+	if v := docString(doc, "SchemaVersion"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("invalid schema version " + v)
+		}
+		version = n
+	}
+	current, _ := strconv.Atoi(schemaVersion)
+	if version > current {
+		return errors.New("schema version " + strconv.Itoa(version) + " is newer than " + schemaVersion)
+	}
+	steps := migrations[docType]
+	for ; version < current; version++ {
+		if version-1 < len(steps) && steps[version-1] != nil {
+			steps[version-1](key, doc, puts)
+		}
+	}
+	doc["DocType"] = docType
+	doc["SchemaVersion"] = schemaVersion
+	return nil
+}
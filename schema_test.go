@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInitDoesNotMigrate(t *testing.T) {
+	s := newTestStub(t)
+	s.begin()
+	s.PutState("u1#home", []byte(`{"State":"서울특별시"}`))
+	s.end()
+	s.begin()
+	new(PS).Init(s, "init", nil)
+	s.end()
+	conf, _ := s.GetState("u1#home")
+	if string(conf) != `{"State":"서울특별시"}` {
+		t.Fatalf("Init rewrote a record: %s", conf)
+	}
+}
+
+func TestMigrateSchemaBatches(t *testing.T) {
+	s := newTestStub(t)
+	s.begin()
+	for _, k := range []string{"u1#home", "u2#home", "u3#home"} {
+		s.PutState(k, []byte(`{"State":"서울특별시"}`))
+	}
+	s.end()
+
+	s.as("ps@example.com")
+	_, err := s.invoke("migrate_schema", "", "2")
+	expectErr(t, err, "migrate_schema by a non-admin")
+
+	s.admin()
+	ret := SchemaMigration{}
+	start := ""
+	for n := 0; n < 10; n++ {
+		json.Unmarshal(s.mustInvoke(t, "migrate_schema", start, "2"), &ret)
+		if ret.Next == "" {
+			break
+		}
+		start = ret.Next
+	}
+	for _, k := range []string{"u1#home", "u2#home", "u3#home"} {
+		homeAsset := HomeAsset{}
+		conf, _ := s.GetState(k)
+		json.Unmarshal(conf, &homeAsset)
+		if homeAsset.SchemaVersion != schemaVersion || homeAsset.ID != defaultHome {
+			t.Fatalf("%s = %+v", k, homeAsset)
+		}
+	}
+}
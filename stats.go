@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+type PetsitterStats struct { // petsitter_stats result
+	PSID             string
+	From             string
+	To               string
+	Trades           string
+	Earnings         string            // TA of completed trades + Payout of cancelled/resolved trades
+	TradesPerMonth   map[string]string // YYYYMM (TS) -> count
+	AvgStayDays      string            // Nights between TS and TE, cancelled trades excluded
+	RepeatCustomer   string            // Rate of consumers with 2+ trades
+	CancellationRate string
+}
+
+// 펫시터 ID, 시작일, 종료일 (본인 또는 관리자, 조건이 없으면 "")
+func (t *PS) petsitter_stats(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Petsitter Stats >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter STATS] Incorrect number of arguments. Expecting 3")
+	}
+	if userID(stub, callerAttr(stub, "email")) != args[0] && !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                         <<<< Petsitter Stats >>>>")
+		fmt.Println("                           Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[Petsitter STATS] Not allowed caller")
+	}
+	f := tradeFilter{From: args[1], To: args[2]}
+	if err := f.validate(); err != nil {
+		return nil, errors.New("[Petsitter STATS] " + err.Error())
+	}
+	var idx string
+	conf, _ := stub.GetState(args[0] + "#t")
+	json.Unmarshal(conf, &idx)
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Petsitter STATS] " + err.Error())
+	}
+	trades := filterTrades(stub, idx, f, now)
+
+	var earnings, nights float64
+	var stays, cancelled int
+	perMonth := map[string]int{}
+	perConsumer := map[string]int{}
+	for _, tradeRec := range trades {
+		if len(tradeRec.TS) >= 6 {
+			perMonth[tradeRec.TS[:6]]++
+		}
+		switch tradeRec.Status {
+		case "cancelled":
+			cancelled++
+			earnings += parseAmount(tradeRec.Payout)
+			continue
+		case "resolved":
+			earnings += parseAmount(tradeRec.Payout)
+		case "disputed":
+		default:
+			if tradeCompleted(tradeRec, now) {
+				earnings += parseAmount(tradeRec.TA)
+			}
+		}
+		perConsumer[tradeRec.CSID]++
+		start, err1 := parseDate(tradeRec.TS)
+		end, err2 := parseDate(tradeRec.TE)
+		if err1 == nil && err2 == nil && !end.Before(start) {
+			nights += end.Sub(start).Hours() / 24
+			stays++
+		}
+	}
+
+	ret := PetsitterStats{}
+	ret.PSID = args[0]
+	ret.From = args[1]
+	ret.To = args[2]
+	ret.Trades = strconv.Itoa(len(trades))
+	ret.Earnings = strconv.FormatFloat(earnings, 'f', 0, 64)
+	ret.TradesPerMonth = map[string]string{}
+	for month, n := range perMonth {
+		ret.TradesPerMonth[month] = strconv.Itoa(n)
+	}
+	ret.AvgStayDays = ratio(nights, stays)
+	repeat := 0
+	for _, n := range perConsumer {
+		if n > 1 {
+			repeat++
+		}
+	}
+	ret.RepeatCustomer = ratio(float64(repeat), len(perConsumer))
+	ret.CancellationRate = ratio(float64(cancelled), len(trades))
+	fmt.Println()
+	fmt.Println("=======================================================================")
+	fmt.Println("                         <<<< Petsitter Stats >>>>")
+	fmt.Println("                          Stats reading success")
+	fmt.Println("=======================================================================")
+	fmt.Println()
+	return json.Marshal(ret)
+}
+
+// 숫자가 아닌 금액은 0
+func parseAmount(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func ratio(sum float64, n int) string {
+	if n == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(sum/float64(n), 'f', 2, 64)
+}
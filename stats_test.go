@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPetsitterStats(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.acceptedTrade(t, "ps@example.com", "cs@example.com", "20240520", "20240525")
+	s.acceptedTrade(t, "ps@example.com", "cs@example.com", "20240620", "20240622")
+
+	s.as("cs@example.com")
+	_, err := s.query("petsitter_stats", psid, "", "")
+	expectErr(t, err, "stats by another user")
+
+	earnings := func() string {
+		s.as("ps@example.com")
+		ret, err := s.query("petsitter_stats", "ps@example.com", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats := PetsitterStats{}
+		json.Unmarshal(ret, &stats)
+		return stats.Earnings
+	}
+	if e := earnings(); e != "100000" {
+		t.Fatalf("earnings = %s", e)
+	}
+	s.now = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	if e := earnings(); e != "200000" {
+		t.Fatalf("earnings = %s", e)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 이메일, 상태(active, paused) (본인 또는 관리자)
+func (t *PS) set_petsitter_status(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 2 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Status >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 2")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter STATUS] Incorrect number of arguments. Expecting 2")
+	}
+	if !selfOrAdmin(stub, args[0]) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Status >>>>")
+		fmt.Println("                           Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter STATUS] Not allowed caller")
+	}
+	if args[1] != "active" && args[1] != "paused" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Status >>>>")
+		fmt.Println("                          Invalid status: " + args[1])
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter STATUS] Invalid status: " + args[1])
+	}
+	conf, _ := stub.GetState(args[0])
+	if conf == nil || petsitterDeleted(conf) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Status >>>>")
+		fmt.Println("                               Not exist Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter STATUS] Not exist Petsitter")
+	}
+	petsitter := Petsitter{}
+	json.Unmarshal(conf, &petsitter)
+	if petsitter.Status == "suspended" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Status >>>>")
+		fmt.Println("                            Suspended Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter STATUS] Suspended Petsitter")
+	}
+	return putPetsitterStatus(stub, args[0], petsitter, args[1])
+}
+
+// 이메일, 사유 (관리자)
+func (t *PS) suspend_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 2 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Suspend >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 2")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter SUSPEND] Incorrect number of arguments. Expecting 2")
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Suspend >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter SUSPEND] Caller is not an admin")
+	}
+	conf, _ := stub.GetState(args[0])
+	if conf == nil || petsitterDeleted(conf) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Petsitter Suspend >>>>")
+		fmt.Println("                               Not exist Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter SUSPEND] Not exist Petsitter")
+	}
+	petsitter := Petsitter{}
+	json.Unmarshal(conf, &petsitter)
+	petsitter.StatusNote = args[1]
+	return putPetsitterStatus(stub, args[0], petsitter, "suspended")
+}
+
+// 이메일 (관리자)
+func (t *PS) reinstate_petsitter(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Petsitter Reinstate >>>>")
+		fmt.Println("               Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter REINSTATE] Incorrect number of arguments. Expecting 1")
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Petsitter Reinstate >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter REINSTATE] Caller is not an admin")
+	}
+	conf, _ := stub.GetState(args[0])
+	if conf == nil || petsitterDeleted(conf) {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Petsitter Reinstate >>>>")
+		fmt.Println("                               Not exist Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter REINSTATE] Not exist Petsitter")
+	}
+	petsitter := Petsitter{}
+	json.Unmarshal(conf, &petsitter)
+	if petsitter.Status != "suspended" {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                      <<<< Petsitter Reinstate >>>>")
+		fmt.Println("                          Not suspended Petsitter")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Petsitter REINSTATE] Not suspended Petsitter")
+	}
+	petsitter.StatusNote = ""
+	return putPetsitterStatus(stub, args[0], petsitter, "active")
+}
+
+func putPetsitterStatus(stub shim.ChaincodeStubInterface, email string, petsitter Petsitter, status string) ([]byte, error) {
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Petsitter STATUS] " + err.Error())
+	}
+	petsitter.Status = status
+	petsitter.SaveTime = now.String()
+	jsonAsBytes, _ := marshalDoc(&petsitter)
+	stub.PutState(email, jsonAsBytes)
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                <<<< Petsitter Status chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return nil, nil
+}
+
+// 상태가 없는 기존 레코드는 active
+func petsitterActive(petsitter Petsitter) bool {
+	return petsitter.Status == "" || petsitter.Status == "active"
+}
+
+func petsitterDeleted(conf []byte) bool {
+	petsitter := Petsitter{}
+	json.Unmarshal(conf, &petsitter)
+	return petsitter.Status == "deleted"
+}
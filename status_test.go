@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSetPetsitterStatusCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+
+	s.as("other@example.com")
+	_, err := s.invoke("set_petsitter_status", psid, "paused")
+	expectErr(t, err, "status change by another user")
+
+	s.as("ps@example.com")
+	s.mustInvoke(t, "set_petsitter_status", "ps@example.com", "paused")
+	s.admin()
+	s.mustInvoke(t, "set_petsitter_status", psid, "active")
+
+	petsitter := Petsitter{}
+	conf, _ := s.GetState(psid)
+	json.Unmarshal(conf, &petsitter)
+	if petsitter.Status != "active" {
+		t.Fatalf("status = %s", petsitter.Status)
+	}
+}
+
+func TestDeletePetsitterCaller(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+
+	s.as("other@example.com")
+	_, err := s.invoke("delete_petsitter", psid)
+	expectErr(t, err, "delete by another user")
+	petsitter := Petsitter{}
+	conf, _ := s.GetState(psid)
+	json.Unmarshal(conf, &petsitter)
+	if petsitter.Status == "deleted" {
+		t.Fatal("petsitter deleted by another user")
+	}
+
+	s.as("ps@example.com")
+	s.mustInvoke(t, "delete_petsitter", "ps@example.com")
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 거래 ID 는 트랜잭션 ID 에서 만든다 (거래 키: PSID#CSID#거래 ID).
+// 이전 거래는 TC 를 거래 ID 로 사용한다.
+func tradeID(stub shim.ChaincodeStubInterface) string {
+	sum := sha256.Sum256([]byte(stub.GetTxID()))
+	return "t" + hex.EncodeToString(sum[:10])
+}
+
+// 고객별 거래 목록 (KEY: CSID#c)
+func consumerIndexKey(csid string) string {
+	return csid + "#c"
+}
+
+func tradeKey(psid, csid, tid string) string {
+	return psid + "#" + csid + "#" + tid
+}
+
+// 중복 요청 확인 (KEY: _idem#sha256(PSID#CSID#멱등 키), 값: 거래 ID)
+func idemKey(psid, csid, idem string) string {
+	sum := sha256.Sum256([]byte(psid + "#" + csid + "#" + idem))
+	return "_idem#" + hex.EncodeToString(sum[:])
+}
+
+// 취소되었거나 없는 거래
+func tradeCancelled(stub shim.ChaincodeStubInterface, key string) bool {
+	valAsbytes, _ := stub.GetState(key)
+	if valAsbytes == nil {
+		return true
+	}
+	tradeRec := TradeRec{}
+	json.Unmarshal(valAsbytes, &tradeRec)
+	return tradeRec.Status == "cancelled"
+}
+
+// 인덱스 문자열에 없을 때만 추가
+func appendIndex(stub shim.ChaincodeStubInterface, key, entry string) {
+	var idx string
+	conf, _ := stub.GetState(key)
+	json.Unmarshal(conf, &idx)
+	for _, v := range splitIndex(idx) {
+		if v == entry {
+			return
+		}
+	}
+	if idx == "" {
+		idx = "/"
+	}
+	ibyte, _ := json.Marshal(idx + entry + "/")
+	stub.PutState(key, ibyte)
+}
+
+type tradeFilter struct { // Trade search filter ("" = no filter)
+	From   string // YYYYMMDD, trades ending on or after
+	To     string // YYYYMMDD, trades starting on or before
+	Status string // booked, accepted, cancelled, disputed, resolved or completed
+	CSID   string
+	MinTA  string // Minimum transaction amount
+	MaxTA  string // Maximum transaction amount
+}
+
+func (f tradeFilter) validate() error {
+	for _, v := range []string{f.From, f.To} {
+		if v == "" {
+			continue
+		}
+		if _, err := parseDate(v); err != nil {
+			return err
+		}
+	}
+	for _, v := range []string{f.MinTA, f.MaxTA} {
+		if v == "" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return errors.New("bad amount " + v)
+		}
+	}
+	return nil
+}
+
+// 완료(completed)는 이용 기간이 끝나고 취소/분쟁 중이 아닌 거래
+func (f tradeFilter) match(tradeRec TradeRec, now time.Time) bool {
+	if f.From != "" && len(tradeRec.TE) >= 8 && tradeRec.TE[:8] < f.From[:8] {
+		return false
+	}
+	if f.To != "" && len(tradeRec.TS) >= 8 && tradeRec.TS[:8] > f.To[:8] {
+		return false
+	}
+	if f.CSID != "" && tradeRec.CSID != f.CSID {
+		return false
+	}
+	if f.MinTA != "" || f.MaxTA != "" {
+		ta, err := strconv.ParseFloat(tradeRec.TA, 64)
+		if err != nil {
+			return false
+		}
+		if min, _ := strconv.ParseFloat(f.MinTA, 64); f.MinTA != "" && ta < min {
+			return false
+		}
+		if max, _ := strconv.ParseFloat(f.MaxTA, 64); f.MaxTA != "" && ta > max {
+			return false
+		}
+	}
+	if f.Status != "" {
+		status := tradeRec.Status
+		if status == "" {
+			status = "booked"
+		}
+		if f.Status == "completed" {
+			return status != "disputed" && tradeCompleted(tradeRec, now)
+		}
+		return status == f.Status
+	}
+	return true
+}
+
+// 인덱스의 거래 중 조건에 맞는 것 (PSNickname 은 키가 있으면 복호화)
+func filterTrades(stub shim.ChaincodeStubInterface, idx string, f tradeFilter, now time.Time) []TradeRec {
+	key, _ := piiKey(stub)
+	ret := []TradeRec{}
+	for _, tkey := range splitIndex(idx) {
+		valAsbytes, _ := stub.GetState(tkey)
+		if valAsbytes == nil {
+			continue
+		}
+		tradeRec := TradeRec{}
+		json.Unmarshal(valAsbytes, &tradeRec)
+		if !f.match(tradeRec, now) {
+			continue
+		}
+		decryptFields(key, tkey, tradePII(&tradeRec))
+		ret = append(ret, tradeRec)
+	}
+	return ret
+}
+
+// 고객 ID, 시작일, 종료일, 상태 (본인 또는 관리자, 조건이 없으면 "")
+func (t *PS) search_tran_by_consumer(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 4 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Consumer Trade Search >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 4")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[Consumer TRADE SEARCH] Incorrect number of arguments. Expecting 4")
+	}
+	if userID(stub, callerAttr(stub, "email")) != args[0] && !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                       <<<< Consumer Trade Search >>>>")
+		fmt.Println("                           Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[Consumer TRADE SEARCH] Not allowed caller")
+	}
+	f := tradeFilter{From: args[1], To: args[2], Status: args[3]}
+	if err := f.validate(); err != nil {
+		return nil, errors.New("[Consumer TRADE SEARCH] " + err.Error())
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, errors.New("[Consumer TRADE SEARCH] " + err.Error())
+	}
+	var idx string
+	conf, _ := stub.GetState(consumerIndexKey(args[0]))
+	json.Unmarshal(conf, &idx)
+	fmt.Println()
+	fmt.Println("=======================================================================")
+	fmt.Println("                       <<<< Consumer Trade Search >>>>")
+	fmt.Println("                           Trade reading success")
+	fmt.Println("=======================================================================")
+	fmt.Println()
+	return json.Marshal(filterTrades(stub, idx, f, now))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSearchTranByConsumer(t *testing.T) {
+	s := newTestStub(t)
+	s.petsitter(t, "ps@example.com")
+	s.acceptedTrade(t, "ps@example.com", "cs@example.com", "20240520", "20240525")
+	s.acceptedTrade(t, "ps@example.com", "cs@example.com", "20240620", "20240622")
+	csid := userID(s, "cs@example.com")
+
+	s.as("other@example.com")
+	_, err := s.query("search_tran_by_consumer", csid, "", "", "")
+	expectErr(t, err, "search by another user")
+
+	// 완료 여부는 트랜잭션 시각 기준
+	count := func() int {
+		s.as("cs@example.com")
+		ret, err := s.query("search_tran_by_consumer", "cs@example.com", "", "", "completed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var trades []TradeRec
+		json.Unmarshal(ret, &trades)
+		return len(trades)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("completed trades = %d", n)
+	}
+	s.now = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	if n := count(); n != 2 {
+		t.Fatalf("completed trades = %d", n)
+	}
+}
+
+func TestSaveTranDuplicate(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	s.now = time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	s.as("cs@example.com")
+	book := func(idem ...string) ([]byte, error) {
+		args := append([]string{psid, "nick", "cs@example.com", "20240620", "20240622", "20240622", "100000", "booked", ""}, idem...)
+		return s.invoke("save_tran", args...)
+	}
+
+	tid := s.mustInvoke(t, "save_tran", psid, "nick", "cs@example.com", "20240620", "20240622", "20240622", "100000", "booked")
+	_, err := book()
+	expectErr(t, err, "resubmitted booking")
+
+	// 멱등 키가 다르면 같은 내용이라도 별도 거래
+	tid2, err := book("key-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = book("key-2")
+	expectErr(t, err, "resubmitted booking with the same key")
+	if string(tid) == string(tid2) {
+		t.Fatalf("trade IDs collide: %s", tid)
+	}
+	var idx string
+	conf, _ := s.GetState(psid + "#t")
+	json.Unmarshal(conf, &idx)
+	if n := len(splitIndex(idx)); n != 2 {
+		t.Fatalf("index = %s", idx)
+	}
+
+	// 취소하면 같은 날짜, 금액으로 다시 예약할 수 있다
+	s.mustInvoke(t, "cancel_booking", psid, userID(s, "cs@example.com"), string(tid))
+	tid3, err := book()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(tid3) == string(tid) {
+		t.Fatalf("rebooked trade reuses %s", tid)
+	}
+}
+
+func TestTradeIDFromTxID(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	tid := s.trade(t, psid, "cs@example.com", "20240620", "20240622")
+	if tid != tradeID(s) || len(tid) != 21 || tid[0] != 't' {
+		t.Fatalf("trade ID = %s", tid)
+	}
+	if !existState(s, tradeKey(psid, userID(s, "cs@example.com"), tid)) {
+		t.Fatal("trade not stored under its ID")
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// 사용자 키는 가입 시 만든 익명 ID 를 사용한다.
+// 이메일 -> ID 매핑은 이메일의 HMAC 을 키로 저장한다 (KEY: _uid#HMAC-SHA256(PS_UID_SECRET, email)).
+// HMAC 키는 원장이 아닌 체인코드 컨테이너의 환경 변수 PS_UID_SECRET 로 받는다. 모든 검증 피어에
+// 같은 값을 설정해야 하며(core.yaml 의 chaincode Dockerfile ENV 등), 설정되지 않으면 호출을 거부한다.
+// 함수 인자로 받은 이메일은 Invoke/Query 에서 ID 로 바뀐다. 매핑이 없는 기존 사용자는 이메일 그대로 사용한다.
+//
+// 한계: Fabric 0.6 은 Invoke 인자를 블록의 트랜잭션에 그대로 기록하므로, 이메일을 인자로 넘기면
+// 평문 이메일이 원장에 남는다. 블록에 기록되지 않는 Query(read_user_id)로 ID 를 얻어 Invoke 에는 ID 를
+// 넘기면 피할 수 있지만, 처음 등록하는 save_petsitter, save_tran(신규 고객), bulk_import,
+// register_petsitter, erase_user 는 이메일 인자가 필요하다. 이 부분은 요구사항을 충족하지 못한다.
+var uidSecret = []byte(os.Getenv("PS_UID_SECRET"))
+
+var userArgs = map[string][]int{
+	"modify_petsitter":         {0},
+	"delete_petsitter":         {0},
+	"save_home_address":        {0},
+	"save_home_room":           {0},
+	"save_home_car_elevator":   {0},
+	"modify_home_address":      {0},
+	"modify_home_room":         {0},
+	"modify_home_car_elevator": {0},
+	"save_tran":                {0, 2},
+	"delete_house":             {0},
+	"save_home":                {0},
+	"modify_home":              {0},
+	"set_cancel_policy":        {0},
+	"cancel_booking":           {0, 1},
+	"submit_review":            {0, 1},
+	"submit_consumer_review":   {0, 1},
+	"reveal_reviews":           {0, 1},
+	"open_dispute":             {0, 1, 3},
+	"add_dispute_evidence":     {0, 1, 3},
+	"resolve_dispute":          {0, 1},
+	"set_petsitter_status":     {0},
+	"suspend_petsitter":        {0},
+	"reinstate_petsitter":      {0},
+	"save_named_home":          {0},
+	"modify_named_home":        {0},
+	"delete_named_home":        {0},
+	"set_home_location":        {0},
+	"accept_booking":           {0, 1},
+	"patch_home":               {0},
+	"patch_petsitter":          {0},
+	"read_petsitter":           {0},
+	"read_house":               {0},
+	"search_tran":              {0, 4},
+	"read_cancel_policy":       {0},
+	"list_reviews":             {0},
+	"read_consumer_rating":     {0},
+	"read_dispute":             {0, 1},
+	"read_named_home":          {0},
+	"list_homes":               {0},
+	"read_home_address":        {0, 1},
+	"search_tran_by_consumer":  {0},
+	"petsitter_stats":          {0},
+}
+
+func resolveUserArgs(stub shim.ChaincodeStubInterface, function string, args []string) []string {
+	pos, ok := userArgs[function]
+	if !ok {
+		return args
+	}
+	ret := make([]string, len(args))
+	copy(ret, args)
+	for _, i := range pos {
+		if i < len(ret) {
+			ret[i] = userID(stub, ret[i])
+		}
+	}
+	return ret
+}
+
+func checkUIDSecret() error {
+	if len(uidSecret) == 0 {
+		return errors.New("PS_UID_SECRET is not set")
+	}
+	return nil
+}
+
+func uidKey(email string) string {
+	mac := hmac.New(sha256.New, uidSecret)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "_uid#" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 키 없는 sha256 매핑 (이전 버전, 다시 등록될 때 HMAC 키로 옮긴다)
+func legacyUIDKey(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "_uid#" + hex.EncodeToString(sum[:])
+}
+
+func lookupUser(stub shim.ChaincodeStubInterface, email string) []byte {
+	valAsbytes, _ := stub.GetState(uidKey(email))
+	if valAsbytes == nil {
+		valAsbytes, _ = stub.GetState(legacyUIDKey(email))
+	}
+	return valAsbytes
+}
+
+// 매핑이 없으면 인자를 그대로 반환 (기존 사용자 또는 이미 ID)
+func userID(stub shim.ChaincodeStubInterface, email string) string {
+	valAsbytes := lookupUser(stub, email)
+	if valAsbytes == nil {
+		return email
+	}
+	return string(valAsbytes)
+}
+
+// 이메일이면 ID 를 만들어 매핑 저장
+func registerUser(stub shim.ChaincodeStubInterface, email string) string {
+	if !strings.Contains(email, "@") {
+		return email
+	}
+	valAsbytes, _ := stub.GetState(uidKey(email))
+	if valAsbytes != nil {
+		return string(valAsbytes)
+	}
+	valAsbytes, _ = stub.GetState(legacyUIDKey(email))
+	if valAsbytes != nil {
+		stub.DelState(legacyUIDKey(email))
+		stub.PutState(uidKey(email), valAsbytes)
+		return string(valAsbytes)
+	}
+	sum := sha256.Sum256([]byte(stub.GetTxID() + "#" + email))
+	id := "u" + hex.EncodeToString(sum[:10])
+	stub.PutState(uidKey(email), []byte(id))
+	return id
+}
+
+// 이메일 -> ID 매핑 삭제
+func deleteUser(stub shim.ChaincodeStubInterface, email string) {
+	stub.DelState(uidKey(email))
+	stub.DelState(legacyUIDKey(email))
+}
+
+// 본인(인증서 email 속성) 또는 관리자만 조회
+func (t *PS) read_user_id(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< User ID Read >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 1")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[User ID QUERY] Incorrect number of arguments. Expecting 1")
+	}
+	if callerAttr(stub, "email") != args[0] && !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< User ID Read >>>>")
+		fmt.Println("                           Not allowed caller")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[User ID QUERY] Not allowed caller")
+	}
+	valAsbytes := lookupUser(stub, args[0])
+	if valAsbytes == nil {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                           <<<< User ID Read >>>>")
+		fmt.Println("                               Not exist User")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return []byte("None"), errors.New("[User ID QUERY] Not exist User")
+	}
+	return valAsbytes, nil
+}
+
+type UserMigration struct { // migrate_user result
+	ID      string
+	Scanned string
+	Moved   string
+	Next    string // Start key of the next batch ("" if done)
+}
+
+// 이메일, 시작 키, 최대 처리 건수 (관리자) - 이메일로 저장된 키와 레코드를 ID 로 옮긴다
+// 결과의 Next 를 다음 호출의 시작 키로 사용하여 여러 트랜잭션에 나누어 처리한다.
+func (t *PS) migrate_user(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 3 {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< User Migrate >>>>")
+		fmt.Println("                Incorrect number of arguments. Expecting 3")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[User MIGRATE] Incorrect number of arguments. Expecting 3")
+	}
+	if !hasRole(stub, "admin") {
+		fmt.Println()
+		fmt.Println("=======================================================================")
+		fmt.Println("                          <<<< User Migrate >>>>")
+		fmt.Println("                           Caller is not an admin")
+		fmt.Println("=======================================================================")
+		fmt.Println()
+		return nil, errors.New("[User MIGRATE] Caller is not an admin")
+	}
+	email := args[0]
+	if !strings.Contains(email, "@") {
+		return nil, errors.New("[User MIGRATE] Not an email: " + email)
+	}
+	limit, err := strconv.Atoi(args[2])
+	if err != nil || limit <= 0 {
+		return nil, errors.New("[User MIGRATE] Invalid limit: " + args[2])
+	}
+	ret, err := migrateKeys(stub, email, registerUser(stub, email), args[1], limit)
+	if err != nil {
+		return nil, errors.New("[User MIGRATE] " + err.Error())
+	}
+	fmt.Println("============================<< SUCCESS >>=============================")
+	fmt.Println("                  <<<< User Migrate chaincode >>>>")
+	fmt.Println("======================================================================")
+
+	return json.Marshal(ret)
+}
+
+// 시작 키부터 최대 limit 개의 키와 값에 남아 있는 이메일을 ID 로 바꾼다 (limit 0 이면 끝까지)
+// 옮긴 키가 뒤쪽 범위에 다시 나와도 이미 바뀌었으므로 그대로 둔다.
+func migrateKeys(stub shim.ChaincodeStubInterface, email, id, start string, limit int) (UserMigration, error) {
+	ret := UserMigration{ID: id}
+	if start == "" {
+		start = firstKey
+	}
+	iter, err := stub.RangeQueryState(start, lastKey)
+	if err != nil {
+		return ret, err
+	}
+	type move struct {
+		key, newKey string
+		val         []byte
+	}
+	var moves []move
+	scanned := 0
+	for iter.HasNext() {
+		key, val, err := iter.Next()
+		if err != nil {
+			iter.Close()
+			return ret, err
+		}
+		if limit > 0 && scanned == limit {
+			ret.Next = key
+			break
+		}
+		scanned++
+		if strings.HasPrefix(key, "_uid#") {
+			continue
+		}
+		newKey := replaceToken(key, email, id)
+		newVal := []byte(replaceToken(string(val), email, id))
+		if newKey != key || !bytes.Equal(newVal, val) {
+			moves = append(moves, move{key, newKey, newVal})
+		}
+	}
+	iter.Close()
+	for _, v := range moves {
+		if v.newKey != v.key {
+			stub.DelState(v.key)
+		}
+		stub.PutState(v.newKey, v.val)
+	}
+	CCstr = replaceToken(CCstr, email, id)
+	stub.PutState("_CCstr", []byte(CCstr))
+	ret.Scanned = strconv.Itoa(scanned)
+	ret.Moved = strconv.Itoa(len(moves))
+	return ret, nil
+}
+
+// 앞뒤가 이메일에 쓰이는 문자가 아닐 때만 바꾼다
+func replaceToken(s, old, repl string) string {
+	var buf bytes.Buffer
+	for {
+		i := strings.Index(s, old)
+		if i < 0 {
+			buf.WriteString(s)
+			return buf.String()
+		}
+		j := i + len(old)
+		if (i == 0 || !emailChar(s[i-1])) && (j == len(s) || !emailChar(s[j])) {
+			buf.WriteString(s[:i])
+			buf.WriteString(repl)
+		} else {
+			buf.WriteString(s[:j])
+		}
+		s = s[j:]
+	}
+}
+
+func emailChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("._%+-@", c) >= 0
+}
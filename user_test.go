@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUIDKeyIsKeyed(t *testing.T) {
+	s := newTestStub(t)
+	psid := s.petsitter(t, "ps@example.com")
+	if existState(s, legacyUIDKey("ps@example.com")) {
+		t.Fatal("unkeyed email hash stored")
+	}
+	if userID(s, "PS@example.com ") != psid {
+		t.Fatal("mapping not found by HMAC key")
+	}
+	uidSecret = []byte("other-secret")
+	if userID(s, "ps@example.com") == psid {
+		t.Fatal("mapping found without the secret")
+	}
+}
+
+func TestLegacyUIDKeyMoved(t *testing.T) {
+	s := newTestStub(t)
+	s.begin()
+	s.PutState(legacyUIDKey("cs@example.com"), []byte("uold"))
+	s.end()
+	if userID(s, "cs@example.com") != "uold" {
+		t.Fatal("legacy mapping not found")
+	}
+	s.begin()
+	if id := registerUser(s, "cs@example.com"); id != "uold" {
+		t.Fatalf("id = %s", id)
+	}
+	s.end()
+	if existState(s, legacyUIDKey("cs@example.com")) || !existState(s, uidKey("cs@example.com")) {
+		t.Fatal("legacy mapping not moved")
+	}
+}
+
+func TestUIDSecretRequired(t *testing.T) {
+	s := newTestStub(t)
+	uidSecret = nil
+	_, err := s.query("read_regions")
+	expectErr(t, err, "query without PS_UID_SECRET")
+}
+
+func TestMigrateUserBatches(t *testing.T) {
+	s := newTestStub(t)
+	// 이메일을 키로 쓰던 이전 버전 레코드
+	s.begin()
+	legacy := map[string]string{
+		"old@example.com":                    `{"Nickname":"old"}`,
+		"old@example.com#home":               `{"State":"서울"}`,
+		"old@example.com#policy":             `{"Type":"strict"}`,
+		"old@example.com#cs@example.com#tc1": `{"PSID":"old@example.com","CSID":"cs@example.com"}`,
+		"old@example.com#t":                  `"/old@example.com#cs@example.com#tc1/"`,
+	}
+	for k, v := range legacy {
+		s.PutState(k, []byte(v))
+	}
+	CCstr = "/old@example.com/"
+	s.PutState("_CCstr", []byte(CCstr))
+	s.end()
+
+	s.as("old@example.com")
+	_, err := s.invoke("migrate_user", "old@example.com", "", "2")
+	expectErr(t, err, "migrate_user by a non-admin")
+
+	s.admin()
+	start := ""
+	var id string
+	for i := 0; ; i++ {
+		if i > 20 {
+			t.Fatal("migrate_user does not finish")
+		}
+		ret := UserMigration{}
+		json.Unmarshal(s.mustInvoke(t, "migrate_user", "old@example.com", start, "2"), &ret)
+		if ret.Scanned != "2" && ret.Next != "" {
+			t.Fatalf("batch %d scanned %s", i, ret.Scanned)
+		}
+		id = ret.ID
+		if ret.Next == "" {
+			break
+		}
+		start = ret.Next
+	}
+	if id == "" || userID(s, "old@example.com") != id {
+		t.Fatalf("id = %s", id)
+	}
+	for k, v := range s.State {
+		if strings.Contains(k, "old@example.com") || strings.Contains(string(v), "old@example.com") {
+			t.Fatalf("email left in %s = %s", k, v)
+		}
+	}
+	if !existState(s, id) || !existState(s, id+"#cs@example.com#tc1") || CCstr != "/"+id+"/" {
+		t.Fatalf("records not moved, CCstr = %s", CCstr)
+	}
+}